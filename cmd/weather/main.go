@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
-	"errors"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/andrqxa/weather-aggregator/internal/api"
+	"github.com/andrqxa/weather-aggregator/internal/clock"
 	"github.com/andrqxa/weather-aggregator/internal/config"
+	"github.com/andrqxa/weather-aggregator/internal/precompute"
 	"github.com/andrqxa/weather-aggregator/internal/scheduler"
 	"github.com/andrqxa/weather-aggregator/internal/storage"
 	"github.com/andrqxa/weather-aggregator/internal/weather"
@@ -21,6 +24,15 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
+// Shutdown runs in order, each step bounded: stop accepting HTTP requests
+// and drain in-flight ones, wait for the background writers (schedulers,
+// prefetcher, retention), then flush the store.
+const (
+	httpShutdownTimeout      = 10 * time.Second
+	schedulerShutdownTimeout = 15 * time.Second
+	storeCloseTimeout        = 10 * time.Second
+)
+
 func initLogger() *slog.Logger {
 	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
@@ -38,18 +50,41 @@ func main() {
 	//Init config
 	cfg := config.Load()
 
-	// Init storage
-	store := storage.NewInMemoryStore()
+	// Init clock and storage
+	clk := clock.Real{}
+	store := storage.NewInMemoryStore(clk)
+	store.SetPreferFresher(cfg.PreferFresherObservation)
 
 	log.Info("configuration loaded",
 		"port", cfg.Port,
 		"fetch_interval", cfg.FetchInterval.String(),
 		"openweathermap_key_set", cfg.OpenWeatherMapAPIKey != "",
 		"weatherapi_key_set", cfg.WeatherAPIKey != "",
+		"openmeteo_enabled", cfg.EnableOpenMeteo,
+		"nws_enabled", cfg.EnableNWS,
 		"request_timeout", cfg.RequestTimeout.String(),
+		"fanout_timeout", cfg.FanoutTimeout.String(),
 		"default_cities", cfg.DefaultCities,
+		"warmup_cities", cfg.WarmupCities,
+		"warmup_interval", cfg.WarmupInterval.String(),
+		"cache_ttl", cfg.CacheTTL.String(),
+		"serve_stale_on_failure", cfg.ServeStaleOnFailure,
+		"source_priority", cfg.SourcePriority,
+		"aggregation_strategy", cfg.AggregationStrategy,
+		"provider_weights", cfg.ProviderWeights,
+		"default_timezone", cfg.DefaultTimezone.String(),
+		"provider_lang", cfg.ProviderLang,
 	)
 
+	// Verify the configured store round-trips data before serving traffic
+	if cfg.StoreSelfTest {
+		if err := storage.SelfTest(store); err != nil {
+			log.Error("store self-test failed", "error", err)
+			os.Exit(1)
+		}
+		log.Info("store self-test passed")
+	}
+
 	// Root context with OS signals for graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(),
 		os.Interrupt,
@@ -59,8 +94,54 @@ func main() {
 	defer stop()
 
 	// Initialize weather providers and service
-	providers := initProviders(cfg)
-	svc := weather.NewService(providers)
+	httpClient := &http.Client{
+		Timeout: cfg.RequestTimeout,
+		Transport: &weather.LimitTransport{
+			MaxBytes: int64(cfg.MaxResponseBytes),
+			Base:     &weather.RequestIDTransport{Header: cfg.RequestIDHeader},
+		},
+	}
+	resolver := weather.NewGeocodingResolver(
+		httpClient,
+		cfg.GeocodingTimeout,
+		cfg.GeocodingConcurrency,
+		cfg.GeocodingCacheTTL,
+		cfg.GeocodingNegativeTTL,
+		clk,
+	)
+	providers := initProviders(cfg, httpClient, resolver, clk)
+	if len(providers) == 0 {
+		log.Error("no weather providers configured; enable OpenMeteo or NWS, or set a provider API key")
+		os.Exit(1)
+	}
+	priority, unknown := weather.ParseSources(cfg.SourcePriority)
+	if len(unknown) > 0 {
+		log.Warn("ignoring unknown sources in SOURCE_PRIORITY", "sources", unknown)
+	}
+	weights, unknown := weather.ParseWeights(cfg.ProviderWeights)
+	if len(unknown) > 0 {
+		log.Warn("ignoring unknown sources in PROVIDER_WEIGHTS", "sources", unknown)
+	}
+	var lang string
+	if cfg.ProviderLang != "" {
+		var err error
+		if lang, err = weather.ParseLanguage(cfg.ProviderLang); err != nil {
+			log.Warn("ignoring PROVIDER_LANG", "error", err)
+		}
+	}
+	svc := weather.NewService(providers, clk,
+		weather.WithMinProviders(cfg.MinProviders),
+		weather.WithSourcePriority(priority),
+		weather.WithAggregation(cfg.AggregationStrategy, weights),
+		weather.WithFanoutTimeout(cfg.FanoutTimeout),
+		weather.WithFanoutStagger(cfg.FanoutStagger),
+		weather.WithCoalesceWindow(cfg.CoalesceWindow),
+		weather.WithResolver(resolver),
+		weather.WithLatencyBudget(cfg.LatencyBudget),
+		weather.WithDefaultLanguage(lang),
+		weather.WithFeelsLike(cfg.FeelsLike),
+		weather.WithSyntheticForecast(cfg.SyntheticForecast),
+	)
 
 	// Initialize scheduler (e.g. 1-day forecast by default).
 	const defaultForecastDays = 1
@@ -72,123 +153,75 @@ func main() {
 		cfg.FetchInterval,
 		cfg.RequestTimeout,
 		defaultForecastDays,
-		log,
+		clk,
+		log.With("group", "default"),
 	)
+	schedulers := map[string]*scheduler.Scheduler{
+		"default": sched,
+	}
 
-	// Start scheduler in background.
-	go sched.Start(ctx)
-
-	// Fiber init
-	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			// Log unexpected/unhandled error
-			slog.Error("unhandled fiber error", "error", err)
-
-			// Do not leak internal details to the client
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "internal server error",
-			})
-		},
-	})
-
-	// Middleware
-	app.Use(logger.New())
-	app.Use(recover.New())
-	app.Use(cors.New())
-
-	// API routing
-	api := app.Group("/api")
-	v1 := api.Group("/v1")
-
-	// Health check
-	v1.Get("/health", func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status":             "ok",
-			"default_cities":     cfg.DefaultCities,
-			"fetch_interval":     cfg.FetchInterval.String(),
-			"openweathermap_key": cfg.OpenWeatherMapAPIKey != "",
-			"weatherapi_key":     cfg.WeatherAPIKey != "",
-			"request_timeout":    cfg.RequestTimeout.String(),
-			"last_fetch":         store.LastFetchTimes(),
-		})
-	})
-
-	weatherGroup := v1.Group("/weather")
-
-	// GET /api/v1/weather/current?city=London
-	weatherGroup.Get("/current", func(c *fiber.Ctx) error {
-		city := c.Query("city")
-		if city == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "city query parameter is required",
-			})
-		}
-
-		// Try cache first
-		if cw, ok := store.GetCurrent(city); ok {
-			return c.JSON(cw)
-		}
+	// Optional warm-up group refreshed on its own, usually slower, cadence.
+	if len(cfg.WarmupCities) > 0 {
+		schedulers["warmup"] = scheduler.NewScheduler(
+			svc,
+			store,
+			cfg.WarmupCities,
+			cfg.WarmupInterval,
+			cfg.RequestTimeout,
+			defaultForecastDays,
+			clk,
+			log.With("group", "warmup"),
+		)
+	}
 
-		ctxReq, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
-		defer cancel()
+	for _, s := range schedulers {
+		s.SetQuarantineAfter(cfg.QuarantineAfter)
+		s.CheckTiming()
+	}
 
-		w, err := svc.GetCurrentWeather(ctxReq, city)
-		if err != nil {
-			return mapServiceError(c, err)
+	// Precompute derived aggregates for scheduled cities as data arrives.
+	aggregates := precompute.NewCache(store, func(city string) bool {
+		for _, s := range schedulers {
+			for _, c := range s.Cities() {
+				if strings.EqualFold(strings.TrimSpace(c), strings.TrimSpace(city)) {
+					return true
+				}
+			}
 		}
-
-		// Save to storage with current time as fetch timestamp
-		store.SaveCurrent(city, w, time.Now().UTC())
-
-		return c.JSON(w)
+		return false
 	})
 
-	// GET /api/v1/weather/forecast?city=London&days=1
-	weatherGroup.Get("/forecast", func(c *fiber.Ctx) error {
-		city := c.Query("city")
-		if city == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "city query parameter is required",
-			})
-		}
-
-		rawDays := c.Query("days")
-
-		if rawDays == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "days query parameter is required",
-			})
-		}
-
-		days, err := strconv.Atoi(rawDays)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "invalid days parameter, expected integer",
-			})
-		}
-		if days < 1 || days > 7 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "days parameter must be in the 1 - 7 limit",
-			})
-		}
+	// Start schedulers in background.
+	var schedWG sync.WaitGroup
+	for _, s := range schedulers {
+		schedWG.Go(func() { s.Start(ctx) })
+	}
 
-		// Try cache first
-		if fc, ok := store.GetForecast(city, days); ok {
-			return c.JSON(fc)
-		}
+	// On-demand prefetches run on a bounded worker pool.
+	prefetcher := scheduler.NewPrefetcher(
+		svc,
+		store,
+		cfg.RequestTimeout,
+		cfg.PrefetchWorkers,
+		cfg.PrefetchQueueSize,
+		log.With("group", "prefetch"),
+	)
+	schedWG.Go(func() { prefetcher.Start(ctx) })
 
-		ctxReq, cancel := context.WithTimeout(context.Background(), cfg.RequestTimeout)
-		defer cancel()
+	// Optional retention sweep, stopped with the schedulers before the
+	// store is closed.
+	if cfg.RetentionPeriod > 0 && cfg.RetentionInterval > 0 {
+		schedWG.Go(func() {
+			storage.RunRetention(ctx, store, cfg.RetentionPeriod, cfg.RetentionInterval, clk, log.With("group", "retention"))
+		})
+	}
 
-		fc, err := svc.GetForecast(ctxReq, city, days)
-		if err != nil {
-			return mapServiceError(c, err)
-		}
+	// Apply reloadable configuration on SIGHUP.
+	cfgHolder := config.NewHolder(cfg)
+	go watchReload(ctx, cfgHolder, schedulers, log)
 
-		store.SaveForecast(city, days, fc, time.Now().UTC())
-
-		return c.JSON(fc)
-	})
+	reload := providerReloader(cfgHolder, svc, httpClient, resolver, clk, log)
+	app := buildApp(cfgHolder, svc, store, schedulers, prefetcher, aggregates, reload, clk, log)
 
 	// Run Fiber server in background
 	go func() {
@@ -206,24 +239,195 @@ func main() {
 	<-ctx.Done()
 	log.Info("shutdown signal received")
 
-	// Stop Fiber gracefully
-	if err := app.Shutdown(); err != nil {
-		log.Error("failed to shutdown server", "error", err)
+	// Stop accepting requests first, so no handler writes after the flush
+	if err := app.ShutdownWithTimeout(httpShutdownTimeout); err != nil {
+		log.Error("failed to shutdown server", "error", err, "timeout", httpShutdownTimeout.String())
 	} else {
 		log.Info("server gracefully stopped")
 	}
 
-	// Scheduler сам завершится по ctx.Done()
-	log.Info("scheduler stopped")
+	// Schedulers stop on ctx.Done(); wait so no save races the store flush
+	if waitTimeout(&schedWG, schedulerShutdownTimeout) {
+		log.Info("scheduler stopped")
+	} else {
+		log.Error("scheduler did not stop in time, closing store anyway",
+			"timeout", schedulerShutdownTimeout.String(),
+		)
+	}
+
+	// Flush the store last, with a bounded timeout
+	closeCtx, cancel := context.WithTimeout(context.Background(), storeCloseTimeout)
+	defer cancel()
+
+	if err := store.Close(closeCtx); err != nil {
+		log.Error("failed to close store", "error", err)
+	} else {
+		log.Info("store closed")
+	}
 }
 
-func initProviders(cfg *config.Config) []weather.Provider {
-	httpClient := &http.Client{
-		Timeout: cfg.RequestTimeout,
+// waitTimeout waits for wg up to d and reports whether it finished.
+func waitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// buildApp wires the HTTP handler, middleware and routes into a Fiber app.
+// It has no side effects, so the app can be driven via app.Test with
+// providers pointed at fake upstreams.
+func buildApp(
+	cfg *config.Holder,
+	svc *weather.Service,
+	store storage.Store,
+	schedulers map[string]*scheduler.Scheduler,
+	prefetcher *scheduler.Prefetcher,
+	aggregates *precompute.Cache,
+	reload api.ProviderReloader,
+	clk clock.Clock,
+	log *slog.Logger,
+) *fiber.App {
+	handler := api.NewHandler(cfg, svc, store, schedulers, prefetcher, aggregates, reload, clk, log)
+
+	// Fiber init
+	app := fiber.New(fiber.Config{
+		ErrorHandler: handler.ErrorHandler,
+		// Store snapshots posted to /admin/import are the largest bodies.
+		BodyLimit: cfg.Current().MaxRequestBodyBytes,
+	})
+
+	// Middleware
+	app.Use(api.RequestID(cfg.Current().RequestIDHeader))
+	app.Use(logger.New())
+	app.Use(recover.New())
+	app.Use(cors.New())
+
+	// API routing
+	api.RegisterRoutes(app, handler)
+
+	return app
+}
+
+// watchReload re-reads configuration on SIGHUP, applies the scheduler
+// settings (DefaultCities, FetchInterval and the warm-up group settings)
+// and publishes the new snapshot to request handlers.
+func watchReload(ctx context.Context, holder *config.Holder, schedulers map[string]*scheduler.Scheduler, log *slog.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Info("SIGHUP received, reloading configuration")
+
+			reloadMu.Lock()
+			cur := holder.Current()
+			next := config.Reload()
+			if next.Port != cur.Port {
+				log.Info("port change requires restart, ignoring",
+					"port", cur.Port,
+					"new_port", next.Port,
+				)
+			}
+
+			// Providers are built at startup; keep reporting what is in use.
+			next.Port = cur.Port
+			next.OpenWeatherMapAPIKey = cur.OpenWeatherMapAPIKey
+			next.WeatherAPIKey = cur.WeatherAPIKey
+			next.EnableNWS = cur.EnableNWS
+
+			for _, s := range schedulers {
+				s.SetQuarantineAfter(next.QuarantineAfter)
+			}
+			schedulers["default"].SetCities(next.DefaultCities)
+			schedulers["default"].SetInterval(next.FetchInterval)
+
+			if warmup, ok := schedulers["warmup"]; ok {
+				warmup.SetCities(next.WarmupCities)
+				warmup.SetInterval(next.WarmupInterval)
+			} else if len(next.WarmupCities) > 0 {
+				log.Info("warm-up group was not configured at startup, restart to enable it")
+			}
+
+			holder.Store(next)
+			reloadMu.Unlock()
+		}
 	}
+}
 
-	providers := []weather.Provider{
-		weather.NewOpenMeteoProvider(httpClient),
+// reloadMu serializes configuration reloads triggered by SIGHUP and by the
+// admin provider reload, which both publish a new snapshot.
+var reloadMu sync.Mutex
+
+// providerReloader returns the admin provider reload: it re-reads the
+// environment, rebuilds the providers and swaps them into svc. Only the
+// provider settings of the new configuration are published; the rest is
+// left to SIGHUP.
+func providerReloader(
+	holder *config.Holder,
+	svc *weather.Service,
+	httpClient *http.Client,
+	resolver weather.CoordinateResolver,
+	clk clock.Clock,
+	log *slog.Logger,
+) api.ProviderReloader {
+	return func() error {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+
+		next := config.Reload()
+		providers := initProviders(next, httpClient, resolver, clk)
+		if len(providers) == 0 {
+			return weather.ErrNoProviders
+		}
+		svc.SetProviders(providers)
+
+		cfg := *holder.Current()
+		cfg.OpenWeatherMapAPIKey = next.OpenWeatherMapAPIKey
+		cfg.WeatherAPIKey = next.WeatherAPIKey
+		cfg.EnableOpenMeteo = next.EnableOpenMeteo
+		cfg.EnableNWS = next.EnableNWS
+		cfg.NWSUserAgent = next.NWSUserAgent
+		cfg.MaxDroppedRatio = next.MaxDroppedRatio
+		holder.Store(&cfg)
+
+		names := make([]string, 0, len(providers))
+		for _, p := range providers {
+			names = append(names, p.Name())
+		}
+		log.Info("providers reloaded",
+			"providers", names,
+			"openweathermap_key_set", cfg.OpenWeatherMapAPIKey != "",
+			"weatherapi_key_set", cfg.WeatherAPIKey != "",
+		)
+		return nil
+	}
+}
+
+func initProviders(cfg *config.Config, httpClient *http.Client, resolver weather.CoordinateResolver, clk clock.Clock) []weather.Provider {
+	var providers []weather.Provider
+
+	if cfg.EnableOpenMeteo {
+		providers = append(providers,
+			weather.NewOpenMeteoProvider(httpClient, resolver,
+				weather.WithOpenMeteoMaxDroppedRatio(cfg.MaxDroppedRatio),
+				weather.WithOpenMeteoStrict(cfg.StrictParsing),
+				weather.WithOpenMeteoDailyBudget(cfg.OpenMeteoBudget),
+				weather.WithOpenMeteoClock(clk),
+			),
+		)
 	}
 
 	if cfg.OpenWeatherMapAPIKey != "" {
@@ -238,24 +442,15 @@ func initProviders(cfg *config.Config) []weather.Provider {
 		)
 	}
 
-	return providers
-}
-
-// mapServiceError converts domain/service errors to HTTP responses.
-func mapServiceError(c *fiber.Ctx, err error) error {
-	switch {
-	case errors.Is(err, weather.ErrCityNotFound):
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "city not found",
-		})
-	case errors.Is(err, weather.ErrProviderUnavailable):
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "weather providers are unavailable",
-		})
-	default:
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "internal server error",
-		})
-
+	if cfg.EnableNWS {
+		providers = append(providers,
+			weather.NewNWSProvider(httpClient, resolver, cfg.NWSUserAgent,
+				weather.WithNWSStrict(cfg.StrictParsing),
+				weather.WithNWSDailyBudget(cfg.NWSBudget),
+				weather.WithNWSClock(clk),
+			),
+		)
 	}
+
+	return providers
 }
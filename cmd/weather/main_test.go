@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/config"
+	"github.com/andrqxa/weather-aggregator/internal/precompute"
+	"github.com/andrqxa/weather-aggregator/internal/scheduler"
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// stubProvider knows London only.
+type stubProvider struct{ source weather.Source }
+
+func (p stubProvider) Name() string { return string(p.source) }
+
+func (p stubProvider) FetchCurrent(_ context.Context, city string) (weather.CurrentWeather, error) {
+	if !strings.EqualFold(city, "London") {
+		return weather.CurrentWeather{}, weather.ErrCityNotFound
+	}
+	return weather.CurrentWeather{
+		City:        city,
+		Temperature: 8,
+		Humidity:    80,
+		WindSpeed:   5,
+		Condition:   weather.ConditionRain,
+		Source:      p.source,
+		ObservedAt:  testNow,
+	}, nil
+}
+
+func (p stubProvider) FetchForecast(_ context.Context, city string, days int) (weather.Forecast, error) {
+	if !strings.EqualFold(city, "London") {
+		return weather.Forecast{}, weather.ErrCityNotFound
+	}
+	var items []weather.ForecastItem
+	for h := range 24 * days {
+		items = append(items, weather.ForecastItem{
+			TimeStamp:   testNow.Truncate(24 * time.Hour).Add(time.Duration(h) * time.Hour),
+			Temperature: 5 + float64(h%24)/4,
+			Humidity:    75,
+			WindSpeed:   4,
+			Condition:   weather.ConditionCloudy,
+			Source:      p.source,
+		})
+	}
+	return weather.Forecast{City: city, Days: days, ActualDays: days, Items: items, Source: p.source}, nil
+}
+
+// newTestApp builds the app around stub providers, without listeners or
+// background workers.
+func newTestApp(t *testing.T, providers ...weather.Provider) *fiber.App {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	clk := clock.NewManual(testNow)
+	cfg := config.NewHolder(config.Load())
+
+	svc := weather.NewService(providers, clk)
+	store := storage.NewInMemoryStore(clk)
+	schedulers := map[string]*scheduler.Scheduler{}
+	prefetcher := scheduler.NewPrefetcher(svc, store, time.Second, 1, 1, log)
+	aggregates := precompute.NewCache(store, func(string) bool { return false })
+
+	return buildApp(cfg, svc, store, schedulers, prefetcher, aggregates, nil, clk, log)
+}
+
+// get performs a GET request and decodes the JSON body into a map.
+func get(t *testing.T, app *fiber.App, target string) (int, map[string]any) {
+	t.Helper()
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, target, nil))
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	defer resp.Body.Close()
+
+	var body map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("GET %s: decode body: %v", target, err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestAppCurrentWeather(t *testing.T) {
+	app := newTestApp(t, stubProvider{weather.SourceOpenMeteo})
+
+	status, body := get(t, app, "/api/v1/weather/current?city=London")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body %v", status, body)
+	}
+	if body["temperature"] != 8.0 || body["condition"] != string(weather.ConditionRain) {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestAppForecast(t *testing.T) {
+	app := newTestApp(t, stubProvider{weather.SourceOpenMeteo})
+
+	status, body := get(t, app, "/api/v1/weather/forecast?city=London&days=2")
+	if status != fiber.StatusOK {
+		t.Fatalf("status = %d, body %v", status, body)
+	}
+	if items, _ := body["items"].([]any); len(items) != 48 {
+		t.Errorf("got %d items, want 48", len(items))
+	}
+}
+
+func TestAppErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		providers []weather.Provider
+		target    string
+		status    int
+		code      string
+	}{
+		{"missing city", nil, "/api/v1/weather/current", fiber.StatusBadRequest, "MISSING_CITY"},
+		{"invalid days", nil, "/api/v1/weather/forecast?city=London&days=abc", fiber.StatusBadRequest, "INVALID_DAYS"},
+		{"unknown city", []weather.Provider{stubProvider{weather.SourceOpenMeteo}}, "/api/v1/weather/current?city=Atlantis", fiber.StatusNotFound, "CITY_NOT_FOUND"},
+		{"no providers", nil, "/api/v1/weather/current?city=London", fiber.StatusServiceUnavailable, "PROVIDERS_UNAVAILABLE"},
+		{"unknown route", nil, "/api/v1/nope", fiber.StatusNotFound, "NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, tt.providers...)
+
+			status, body := get(t, app, tt.target)
+			if status != tt.status || body["code"] != tt.code {
+				t.Errorf("got %d %v, want %d %s", status, body["code"], tt.status, tt.code)
+			}
+		})
+	}
+}
+
+func TestAppHealth(t *testing.T) {
+	app := newTestApp(t, stubProvider{weather.SourceOpenMeteo})
+
+	status, body := get(t, app, "/api/v1/health")
+	if status != fiber.StatusOK || body["status"] != "ok" {
+		t.Fatalf("got %d %v", status, body)
+	}
+	if providers, _ := body["providers"].([]any); len(providers) != 1 || providers[0] != "openmeteo" {
+		t.Errorf("providers = %v, want [openmeteo]", body["providers"])
+	}
+}
+
+func TestAppListsCitiesByDisplayName(t *testing.T) {
+	app := newTestApp(t, stubProvider{weather.SourceOpenMeteo})
+
+	for _, city := range []string{"London", "LONDON"} {
+		if status, body := get(t, app, "/api/v1/weather/current?city="+city); status != fiber.StatusOK {
+			t.Fatalf("status = %d, body %v", status, body)
+		}
+	}
+
+	_, body := get(t, app, "/api/v1/cities")
+	cities, _ := body["cities"].([]any)
+	if len(cities) != 1 {
+		t.Fatalf("cities = %v, want one", body["cities"])
+	}
+	if city, _ := cities[0].(map[string]any); city["name"] != "London" || city["last_fetch"] == nil {
+		t.Errorf("city = %v, want London with its last fetch", city)
+	}
+
+	if _, body := get(t, app, "/api/v1/cache?city=london"); body["city"] != "London" {
+		t.Errorf("cache info city = %v, want London", body["city"])
+	}
+}
+
+// getXML performs a GET request accepting accept and decodes the XML body
+// into v. It returns the status and content type.
+func getXML(t *testing.T, app *fiber.App, target, accept string, v any) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(fiber.MethodGet, target, nil)
+	req.Header.Set(fiber.HeaderAccept, accept)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	defer resp.Body.Close()
+
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: decode body: %v", target, err)
+	}
+	return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType)
+}
+
+func TestAppXML(t *testing.T) {
+	app := newTestApp(t, stubProvider{weather.SourceOpenMeteo})
+
+	t.Run("current", func(t *testing.T) {
+		var body struct {
+			XMLName     xml.Name `xml:"response"`
+			City        string   `xml:"city"`
+			Temperature float64  `xml:"temperature"`
+			Sources     []string `xml:"sources>item"`
+		}
+		status, ct := getXML(t, app, "/api/v1/weather/current?city=London", fiber.MIMEApplicationXML, &body)
+		if status != fiber.StatusOK || ct != fiber.MIMEApplicationXMLCharsetUTF8 {
+			t.Fatalf("got %d %s", status, ct)
+		}
+		if body.City != "London" || body.Temperature != 8 || len(body.Sources) != 1 || body.Sources[0] != "openmeteo" {
+			t.Errorf("unexpected body %+v", body)
+		}
+	})
+
+	t.Run("forecast", func(t *testing.T) {
+		var body struct {
+			XMLName xml.Name `xml:"response"`
+			Items   []struct {
+				Timestamp string `xml:"timestamp"`
+			} `xml:"items>item"`
+		}
+		status, _ := getXML(t, app, "/api/v1/weather/forecast?city=London&days=2", "text/xml", &body)
+		if status != fiber.StatusOK {
+			t.Fatalf("status = %d", status)
+		}
+		if len(body.Items) != 48 || body.Items[0].Timestamp == "" {
+			t.Errorf("got %d items, first %+v, want 48 with timestamps", len(body.Items), body.Items)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var body struct {
+			XMLName xml.Name `xml:"response"`
+			Code    string   `xml:"code"`
+			Error   string   `xml:"error"`
+		}
+		status, ct := getXML(t, app, "/api/v1/weather/current?city=Atlantis", fiber.MIMEApplicationXML, &body)
+		if status != fiber.StatusNotFound || ct != fiber.MIMEApplicationXMLCharsetUTF8 {
+			t.Fatalf("got %d %s", status, ct)
+		}
+		if body.Code != "CITY_NOT_FOUND" || body.Error == "" {
+			t.Errorf("unexpected body %+v", body)
+		}
+	})
+
+	t.Run("problem", func(t *testing.T) {
+		var body struct {
+			XMLName xml.Name `xml:"problem"`
+			Status  int      `xml:"status"`
+			Code    string   `xml:"code"`
+		}
+		status, ct := getXML(t, app, "/api/v1/weather/current", "application/problem+xml", &body)
+		if status != fiber.StatusBadRequest || ct != "application/problem+xml" {
+			t.Fatalf("got %d %s", status, ct)
+		}
+		if body.Status != fiber.StatusBadRequest || body.Code != "MISSING_CITY" {
+			t.Errorf("unexpected body %+v", body)
+		}
+	})
+}
+
+func TestAppEvictCacheRequiresAdmin(t *testing.T) {
+	t.Setenv("ADMIN_API_KEY", "secret")
+	app := newTestApp(t, stubProvider{source: weather.SourceOpenMeteo})
+
+	if status, body := get(t, app, "/api/v1/weather/current?city=London"); status != fiber.StatusOK {
+		t.Fatalf("status = %d, body %v", status, body)
+	}
+
+	evict := func(target, key string) int {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodDelete, target, nil)
+		if key != "" {
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer "+key)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("DELETE %s: %v", target, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := evict("/api/v1/cache?city=London", "secret"); status == fiber.StatusOK {
+		t.Errorf("DELETE /api/v1/cache status = %d, want it gone", status)
+	}
+	if status := evict("/api/v1/admin/cache?city=London", ""); status != fiber.StatusUnauthorized {
+		t.Errorf("unauthenticated evict status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+	if _, body := get(t, app, "/api/v1/cache?city=London"); body["current"] == nil {
+		t.Fatalf("cache info = %v, want London still cached", body)
+	}
+
+	if status := evict("/api/v1/admin/cache?city=London", "secret"); status != fiber.StatusOK {
+		t.Fatalf("admin evict status = %d, want %d", status, fiber.StatusOK)
+	}
+	if _, body := get(t, app, "/api/v1/cache?city=London"); body["current"] != nil {
+		t.Errorf("cache info = %v, want London evicted", body)
+	}
+}
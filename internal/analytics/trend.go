@@ -0,0 +1,101 @@
+// Package analytics derives indicators from stored weather history.
+package analytics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+)
+
+// steadySlope is the absolute temperature slope (Celsius per hour) below
+// which a trend is reported as steady.
+const steadySlope = 0.1
+
+// ErrInsufficientHistory is returned when fewer than two snapshots exist.
+var ErrInsufficientHistory = errors.New("insufficient history")
+
+// Direction labels the overall temperature trend.
+type Direction string
+
+const (
+	DirectionRising  Direction = "rising"
+	DirectionFalling Direction = "falling"
+	DirectionSteady  Direction = "steady"
+)
+
+// Delta is the temperature change between two consecutive snapshots.
+type Delta struct {
+	From   time.Time
+	To     time.Time
+	Change float64 // Celsius
+}
+
+// Trend summarizes the temperature change over a series of snapshots.
+type Trend struct {
+	Points       int
+	Deltas       []Delta
+	SlopePerHour float64 // Celsius per hour, least-squares fit
+	Direction    Direction
+}
+
+// TemperatureTrend computes per-interval temperature deltas and a linear
+// regression slope over snapshots, which must be ordered oldest first.
+// It returns ErrInsufficientHistory for fewer than two snapshots.
+func TemperatureTrend(snapshots []storage.CurrentSnapshot) (Trend, error) {
+	if len(snapshots) < 2 {
+		return Trend{}, ErrInsufficientHistory
+	}
+
+	deltas := make([]Delta, 0, len(snapshots)-1)
+	for i := 1; i < len(snapshots); i++ {
+		prev, cur := snapshots[i-1], snapshots[i]
+		deltas = append(deltas, Delta{
+			From:   prev.At,
+			To:     cur.At,
+			Change: cur.Data.Temperature - prev.Data.Temperature,
+		})
+	}
+
+	slope := regressionSlope(snapshots)
+
+	dir := DirectionSteady
+	switch {
+	case slope >= steadySlope:
+		dir = DirectionRising
+	case slope <= -steadySlope:
+		dir = DirectionFalling
+	}
+
+	return Trend{
+		Points:       len(snapshots),
+		Deltas:       deltas,
+		SlopePerHour: slope,
+		Direction:    dir,
+	}, nil
+}
+
+// regressionSlope fits temperature against hours since the first snapshot
+// by ordinary least squares. It returns 0 if all snapshots share a time.
+func regressionSlope(snapshots []storage.CurrentSnapshot) float64 {
+	start := snapshots[0].At
+	n := float64(len(snapshots))
+
+	var sumX, sumY float64
+	for _, s := range snapshots {
+		sumX += s.At.Sub(start).Hours()
+		sumY += s.Data.Temperature
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var num, den float64
+	for _, s := range snapshots {
+		dx := s.At.Sub(start).Hours() - meanX
+		num += dx * (s.Data.Temperature - meanY)
+		den += dx * dx
+	}
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
@@ -0,0 +1,98 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+var testStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// series returns snapshots with the given temperatures, one per interval.
+func series(interval time.Duration, temps ...float64) []storage.CurrentSnapshot {
+	res := make([]storage.CurrentSnapshot, 0, len(temps))
+	for i, temp := range temps {
+		res = append(res, storage.CurrentSnapshot{
+			At:   testStart.Add(time.Duration(i) * interval),
+			Data: weather.CurrentWeather{Temperature: temp},
+		})
+	}
+	return res
+}
+
+func TestTemperatureTrendInsufficientHistory(t *testing.T) {
+	for _, snaps := range [][]storage.CurrentSnapshot{nil, series(time.Hour, 10)} {
+		if _, err := TemperatureTrend(snaps); !errors.Is(err, ErrInsufficientHistory) {
+			t.Errorf("%d snapshots: err = %v, want ErrInsufficientHistory", len(snaps), err)
+		}
+	}
+}
+
+func TestRegressionSlope(t *testing.T) {
+	tests := []struct {
+		name  string
+		snaps []storage.CurrentSnapshot
+		want  float64
+	}{
+		{"rising by 2 °C per hour", series(time.Hour, 10, 12, 14), 2},
+		{"falling over half hours", series(30*time.Minute, 10, 9, 8), -2},
+		{"noisy fit", series(time.Hour, 10, 13, 12, 15), 1.4},
+		{"same timestamp", series(0, 10, 20, 30), 0},
+	}
+
+	for _, tt := range tests {
+		if got := regressionSlope(tt.snaps); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: slope = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTemperatureTrendDirection(t *testing.T) {
+	tests := []struct {
+		name  string
+		snaps []storage.CurrentSnapshot
+		want  Direction
+	}{
+		{"rising", series(time.Hour, 10, 11, 12), DirectionRising},
+		{"rising exactly at the threshold", series(time.Hour, 0, steadySlope), DirectionRising},
+		{"steady just below the threshold", series(time.Hour, 10, 10+steadySlope/2), DirectionSteady},
+		{"steady flat", series(time.Hour, 10, 10, 10), DirectionSteady},
+		{"steady just above the negative threshold", series(time.Hour, 10, 10-steadySlope/2), DirectionSteady},
+		{"falling exactly at the threshold", series(time.Hour, 0, -steadySlope), DirectionFalling},
+		{"falling", series(time.Hour, 12, 11, 10), DirectionFalling},
+		{"same timestamp", series(0, 10, 20), DirectionSteady},
+	}
+
+	for _, tt := range tests {
+		trend, err := TemperatureTrend(tt.snaps)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if trend.Direction != tt.want {
+			t.Errorf("%s: direction = %s (slope %v), want %s", tt.name, trend.Direction, trend.SlopePerHour, tt.want)
+		}
+	}
+}
+
+func TestTemperatureTrendDeltas(t *testing.T) {
+	trend, err := TemperatureTrend(series(time.Hour, 10, 12.5, 11))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trend.Points != 3 {
+		t.Errorf("points = %d, want 3", trend.Points)
+	}
+	want := []Delta{
+		{From: testStart, To: testStart.Add(time.Hour), Change: 2.5},
+		{From: testStart.Add(time.Hour), To: testStart.Add(2 * time.Hour), Change: -1.5},
+	}
+	if !slices.Equal(trend.Deltas, want) {
+		t.Errorf("deltas = %+v, want %+v", trend.Deltas, want)
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Error response formats.
+const (
+	errorFormatSimple  = "simple"
+	errorFormatProblem = "problem"
+
+	problemContentType    = "application/problem+json"
+	problemXMLContentType = "application/problem+xml"
+)
+
+// Machine-readable error codes. They are part of the API contract:
+// clients branch on them, so existing values must not change.
+const (
+	codeMissingCity          = "MISSING_CITY"
+	codeInvalidDays          = "INVALID_DAYS"
+	codeInvalidHours         = "INVALID_HOURS"
+	codeInvalidFields        = "INVALID_FIELDS"
+	codeInvalidParameter     = "INVALID_PARAMETER"
+	codeCityNotFound         = "CITY_NOT_FOUND"
+	codeInsufficientHistory  = "INSUFFICIENT_HISTORY"
+	codeProvidersUnavailable = "PROVIDERS_UNAVAILABLE"
+	codeProvidersTimeout     = "PROVIDERS_TIMEOUT"
+	codeRateLimited          = "RATE_LIMITED"
+	codePrefetchQueueFull    = "PREFETCH_QUEUE_FULL"
+	codeNoProviders          = "NO_PROVIDERS"
+	codeUnauthorized         = "UNAUTHORIZED"
+	codeForbidden            = "FORBIDDEN"
+	codeNotFound             = "NOT_FOUND"
+	codeBadRequest           = "BAD_REQUEST"
+	codeInternal             = "INTERNAL"
+)
+
+// problemDetails is an RFC 7807 error object; Code is an extension member.
+type problemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+
+	DidYouMean string `json:"did_you_mean,omitempty"`
+}
+
+// writeError writes an error response. The simple {"error": "...", "code": "..."}
+// shape is the default; RFC 7807 problem details are used when configured or
+// when the client accepts application/problem+json.
+// Both honor XML content negotiation like successful responses.
+func (h *Handler) writeError(c *fiber.Ctx, status int, code, detail string) error {
+	return h.writeErrorWith(c, status, code, detail, "")
+}
+
+// writeErrorWith is writeError with a suggested city, reported as
+// did_you_mean unless empty.
+func (h *Handler) writeErrorWith(c *fiber.Ctx, status int, code, detail, didYouMean string) error {
+	if !h.wantsProblem(c) {
+		body := fiber.Map{
+			"error": detail,
+			"code":  code,
+		}
+		if didYouMean != "" {
+			body["did_you_mean"] = didYouMean
+		}
+		return respond(c.Status(status), body)
+	}
+
+	problem := problemDetails{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.OriginalURL(),
+		Code:     code,
+
+		DidYouMean: didYouMean,
+	}
+	if wantsXML(c) || strings.Contains(c.Get(fiber.HeaderAccept), problemXMLContentType) {
+		return sendXML(c.Status(status), problem, "problem", problemXMLContentType)
+	}
+	return c.Status(status).JSON(problem, problemContentType)
+}
+
+func (h *Handler) wantsProblem(c *fiber.Ctx) bool {
+	if h.cfg.Current().ErrorFormat == errorFormatProblem {
+		return true
+	}
+	accept := c.Get(fiber.HeaderAccept)
+	return strings.Contains(accept, problemContentType) || strings.Contains(accept, problemXMLContentType)
+}
+
+// mapServiceError converts domain/service errors to HTTP responses.
+// Temporary failures (503, 504, 429) carry a Retry-After header so clients
+// back off instead of retrying immediately. When providers rate limited
+// us, it is the longest delay they asked for.
+func (h *Handler) mapServiceError(c *fiber.Ctx, err error) error {
+	status, code, msg := serviceErrorStatus(err)
+	switch status {
+	case fiber.StatusServiceUnavailable, fiber.StatusGatewayTimeout, fiber.StatusTooManyRequests:
+		d := h.cfg.Current().RetryAfter
+		var rl *weather.RateLimitError
+		if errors.As(err, &rl) && rl.RetryAfter > 0 {
+			d = rl.RetryAfter
+		}
+		setRetryAfter(c, d)
+	}
+	return h.writeError(c, status, code, msg)
+}
+
+// setRetryAfter sets the Retry-After header to d rounded up to whole
+// seconds, so clients never retry early; d <= 0 sets nothing.
+func setRetryAfter(c *fiber.Ctx, d time.Duration) {
+	if d > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(d.Seconds()))))
+	}
+}
+
+// serviceErrorStatus returns the HTTP status, error code and client-safe
+// message for a domain/service error.
+func serviceErrorStatus(err error) (int, string, string) {
+	switch {
+	case errors.Is(err, weather.ErrCityNotFound):
+		return fiber.StatusNotFound, codeCityNotFound, "city not found"
+	case errors.Is(err, weather.ErrRateLimited):
+		return fiber.StatusTooManyRequests, codeRateLimited, "weather providers are rate limiting requests"
+	case errors.Is(err, weather.ErrProviderUnavailable):
+		return fiber.StatusServiceUnavailable, codeProvidersUnavailable, "weather providers are unavailable"
+	case errors.Is(err, weather.ErrTimeout):
+		return fiber.StatusGatewayTimeout, codeProvidersTimeout, "weather providers did not answer in time"
+	default:
+		return fiber.StatusInternalServerError, codeInternal, "internal server error"
+	}
+}
+
+// isTemporary reports whether err is a transient provider failure, for
+// which stale cached data may be served instead.
+func isTemporary(err error) bool {
+	return errors.Is(err, weather.ErrProviderUnavailable) || errors.Is(err, weather.ErrTimeout)
+}
+
+// ErrorHandler handles errors not handled by the route handlers.
+// It does not leak internal details to the client.
+func (h *Handler) ErrorHandler(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) && fe.Code < fiber.StatusInternalServerError {
+		code := codeBadRequest
+		if fe.Code == fiber.StatusNotFound {
+			code = codeNotFound
+		}
+		return h.writeError(c, fe.Code, code, fe.Message)
+	}
+
+	// Log unexpected/unhandled error
+	h.log.Error("unhandled fiber error", "error", err)
+
+	return h.writeError(c, fiber.StatusInternalServerError, codeInternal, "internal server error")
+}
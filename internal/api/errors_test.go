@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/config"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMapServiceError(t *testing.T) {
+	h := &Handler{cfg: config.NewHolder(&config.Config{RetryAfter: 30 * time.Second})}
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter string
+	}{
+		{"rate limited", &weather.RateLimitError{RetryAfter: 1500 * time.Millisecond}, fiber.StatusTooManyRequests, "2"},
+		{"rate limited without delay", &weather.RateLimitError{}, fiber.StatusTooManyRequests, "30"},
+		{"unavailable", weather.ErrProviderUnavailable, fiber.StatusServiceUnavailable, "30"},
+		{"timeout", weather.ErrTimeout, fiber.StatusGatewayTimeout, "30"},
+		{"not found", weather.ErrCityNotFound, fiber.StatusNotFound, ""},
+		{"internal", errors.New("boom"), fiber.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error { return h.mapServiceError(c, tt.err) })
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get(fiber.HeaderRetryAfter); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
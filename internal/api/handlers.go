@@ -1 +1,1201 @@
 package api
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/analytics"
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/config"
+	"github.com/andrqxa/weather-aggregator/internal/precompute"
+	"github.com/andrqxa/weather-aggregator/internal/scheduler"
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxForecastHours is the upper bound for the forecast `hours` parameter.
+const maxForecastHours = 48
+
+// daysValidationLenient clamps out-of-range forecast days instead of
+// rejecting them.
+const daysValidationLenient = "lenient"
+
+// maxBatchCities caps the number of comma-separated cities per request.
+const maxBatchCities = 10
+
+// mimeNDJSON is the streaming format offered for multi-city responses.
+const mimeNDJSON = "application/x-ndjson"
+
+// Handler serves the HTTP API on top of the weather service and storage.
+type Handler struct {
+	cfg        *config.Holder
+	svc        *weather.Service
+	store      storage.Store
+	schedulers map[string]*scheduler.Scheduler
+	prefetcher *scheduler.Prefetcher
+	aggregates *precompute.Cache
+	reload     ProviderReloader
+	latency    *latencyHistogram
+	ready      *readiness
+	clock      clock.Clock
+	log        *slog.Logger
+}
+
+// ProviderReloader rebuilds the providers from the current environment
+// and swaps them into the service. It returns weather.ErrNoProviders,
+// keeping the providers in use, if the new configuration enables none.
+type ProviderReloader func() error
+
+// NewHandler creates a new Handler. Configuration is read from cfg where
+// it is used, so a reload takes effect on the next read, possibly in the
+// middle of a request.
+// Schedulers are keyed by group name and reported by the health endpoint.
+// The prefetcher serves POST /weather/prefetch; aggregates, if not nil,
+// provides precomputed daily summaries; reload serves the admin provider
+// reload. If clk is nil, the real clock is used.
+func NewHandler(
+	cfg *config.Holder,
+	svc *weather.Service,
+	store storage.Store,
+	schedulers map[string]*scheduler.Scheduler,
+	prefetcher *scheduler.Prefetcher,
+	aggregates *precompute.Cache,
+	reload ProviderReloader,
+	clk clock.Clock,
+	log *slog.Logger,
+) *Handler {
+	return &Handler{
+		cfg:        cfg,
+		svc:        svc,
+		store:      store,
+		schedulers: schedulers,
+		prefetcher: prefetcher,
+		aggregates: aggregates,
+		reload:     reload,
+		latency:    newLatencyHistogram(),
+		ready:      &readiness{},
+		clock:      clock.OrReal(clk),
+		log:        log,
+	}
+}
+
+// currentResponse is the current weather payload; FetchedAt is when the
+// data was fetched from the providers, as opposed to ObservedAt, when the
+// providers observed it. Stale marks data served from an expired cache
+// entry because all providers failed, RefreshSkipped a refresh request
+// answered from a cache entry younger than MIN_REFRESH_INTERVAL, and
+// Partial a live result some of the called providers failed to
+// contribute to.
+type currentResponse struct {
+	weather.CurrentWeather
+	FetchedAt      time.Time `json:"fetched_at"`
+	Stale          bool      `json:"stale,omitempty"`
+	RefreshSkipped bool      `json:"refresh_skipped,omitempty"`
+	Partial        bool      `json:"partial,omitempty"`
+}
+
+// forecastResponse is the forecast payload; FetchedAt, Stale and
+// RefreshSkipped have the same meaning as in currentResponse.
+type forecastResponse struct {
+	weather.Forecast
+	Daily          []weather.DailySummary `json:"daily,omitempty"`
+	FetchedAt      time.Time              `json:"fetched_at"`
+	Stale          bool                   `json:"stale,omitempty"`
+	RefreshSkipped bool                   `json:"refresh_skipped,omitempty"`
+}
+
+// batchEntry is one city's result in a multi-city response: either
+// Weather (current) or Forecast, or Status/Code/Error is set.
+type batchEntry struct {
+	City     string `json:"city"`
+	Weather  any    `json:"weather,omitempty"`
+	Forecast any    `json:"forecast,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// fail records err in the entry.
+func (e *batchEntry) fail(err error) {
+	e.Status, e.Code, e.Error = serviceErrorStatus(err)
+}
+
+// lookup records how a response was obtained, for the access log.
+type lookup struct {
+	cacheStatus string
+	report      *weather.Report // nil when served from the fresh cache
+}
+
+func (l lookup) record(c *fiber.Ctx) {
+	setCacheStatus(c, l.cacheStatus)
+	if l.report != nil {
+		setProviders(c, *l.report)
+	}
+}
+
+// cacheEntryResponse describes a single cached entry without its payload.
+type cacheEntryResponse struct {
+	Days      int       `json:"days,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Age       string    `json:"age"`
+}
+
+// cacheResponse describes what is cached for a city.
+type cacheResponse struct {
+	City      string               `json:"city"`
+	Current   *cacheEntryResponse  `json:"current,omitempty"`
+	Forecasts []cacheEntryResponse `json:"forecasts"`
+}
+
+// cityResponse describes a city known to the store.
+type cityResponse struct {
+	Name      string    `json:"name"`
+	LastFetch time.Time `json:"last_fetch"`
+	Scheduled bool      `json:"scheduled"`
+}
+
+// schedulerGroupResponse describes a scheduled group of cities.
+type schedulerGroupResponse struct {
+	Cities   []string `json:"cities"`
+	Interval string   `json:"interval"`
+}
+
+// freshnessResponse reports whether every scheduled city was fetched
+// within its freshness window (FRESHNESS_SLA_FACTOR × group interval).
+type freshnessResponse struct {
+	Fresh  bool                `json:"fresh"`
+	Cities []cityFreshResponse `json:"cities"`
+}
+
+type cityFreshResponse struct {
+	City      string     `json:"city"`
+	Group     string     `json:"group"`
+	LastFetch *time.Time `json:"last_fetch"` // nil if never fetched
+	Window    string     `json:"window"`
+	Fresh     bool       `json:"fresh"`
+}
+
+// providerResponse describes a configured provider.
+type providerResponse struct {
+	Name            string          `json:"name"`
+	Current         bool            `json:"current"`
+	Forecast        bool            `json:"forecast"`
+	MaxForecastDays int             `json:"max_forecast_days,omitempty"`
+	Regional        bool            `json:"regional"`
+	Samples         int             `json:"latency_samples"`
+	P95             string          `json:"latency_p95"`
+	Degraded        bool            `json:"degraded"`
+	Budget          *budgetResponse `json:"budget,omitempty"`
+}
+
+// budgetResponse is a provider's rate-limit budget for the current UTC
+// day; Limit and Remaining are omitted while unknown.
+type budgetResponse struct {
+	Limit     int       `json:"limit,omitempty"`
+	Used      int       `json:"used"`
+	Remaining *int      `json:"remaining,omitempty"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// Providers handles GET /api/v1/providers and lists the configured
+// providers with their capabilities, recent latency, degraded state and
+// rate-limit budget.
+func (h *Handler) Providers(c *fiber.Ctx) error {
+	statuses := h.svc.ProviderStatus()
+
+	resp := make([]providerResponse, 0, len(statuses))
+	for _, st := range statuses {
+		var budget *budgetResponse
+		if b := st.Budget; b != nil {
+			budget = &budgetResponse{Limit: b.Limit, Used: b.Used, ResetAt: b.ResetAt}
+			if b.Limited {
+				budget.Remaining = &b.Remaining
+			}
+		}
+		resp = append(resp, providerResponse{
+			Name:            st.Name,
+			Current:         st.Capabilities.Current,
+			Forecast:        st.Capabilities.Forecast,
+			MaxForecastDays: st.Capabilities.MaxForecastDays,
+			Regional:        st.Capabilities.Region != nil,
+			Samples:         st.Samples,
+			P95:             st.P95.String(),
+			Degraded:        st.Degraded,
+			Budget:          budget,
+		})
+	}
+
+	return respond(c, fiber.Map{"providers": resp})
+}
+
+// ReloadProviders handles POST /api/v1/admin/providers/reload. It rebuilds
+// the providers from the provider API keys and switches in the environment
+// (and .env file), so keys can be rotated without a restart, and responds
+// with the providers now in use like GET /api/v1/providers.
+func (h *Handler) ReloadProviders(c *fiber.Ctx) error {
+	if h.reload == nil {
+		return h.writeError(c, fiber.StatusNotFound, codeNotFound, "provider reload is not available")
+	}
+
+	if err := h.reload(); err != nil {
+		if errors.Is(err, weather.ErrNoProviders) {
+			return h.writeError(c, fiber.StatusConflict, codeNoProviders, "new configuration enables no provider, keeping the current ones")
+		}
+		h.log.Error("failed to reload providers", "error", err)
+		return h.writeError(c, fiber.StatusInternalServerError, codeInternal, "internal server error")
+	}
+
+	return h.Providers(c)
+}
+
+// schedulerStatusResponse describes a scheduler group's state.
+type schedulerStatusResponse struct {
+	Cities   []string         `json:"cities"`
+	Interval string           `json:"interval"`
+	Running  bool             `json:"running"`
+	LastRun  *lastRunResponse `json:"last_run"`
+
+	Quarantined []quarantinedResponse `json:"quarantined"`
+}
+
+// quarantinedResponse describes a city the scheduler gave up on.
+type quarantinedResponse struct {
+	City     string    `json:"city"`
+	Since    time.Time `json:"since"`
+	Failures int       `json:"failures"`
+}
+
+// lastRunResponse describes the last completed scheduler run.
+type lastRunResponse struct {
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+}
+
+// SchedulerStatus handles GET /api/v1/scheduler/status and reports the
+// state and last run of every scheduler group.
+func (h *Handler) SchedulerStatus(c *fiber.Ctx) error {
+	groups := make(map[string]schedulerStatusResponse, len(h.schedulers))
+	for name, s := range h.schedulers {
+		st := s.Status()
+		resp := schedulerStatusResponse{
+			Cities:   st.Cities,
+			Interval: st.Interval.String(),
+			Running:  st.Running,
+
+			Quarantined: make([]quarantinedResponse, 0, len(st.Quarantined)),
+		}
+		for _, q := range st.Quarantined {
+			resp.Quarantined = append(resp.Quarantined, quarantinedResponse{
+				City:     q.City,
+				Since:    q.Since,
+				Failures: q.Failures,
+			})
+		}
+		if run := st.LastRun; run != nil {
+			resp.LastRun = &lastRunResponse{
+				StartedAt: run.StartedAt,
+				Duration:  run.Duration.String(),
+				Succeeded: run.Succeeded,
+				Failed:    run.Failed,
+			}
+		}
+		groups[name] = resp
+	}
+
+	return respond(c, fiber.Map{"groups": groups})
+}
+
+// Health returns service status and configuration summary.
+func (h *Handler) Health(c *fiber.Ctx) error {
+	cfg := h.cfg.Current()
+
+	groups := make(map[string]schedulerGroupResponse, len(h.schedulers))
+	for name, s := range h.schedulers {
+		groups[name] = schedulerGroupResponse{
+			Cities:   s.Cities(),
+			Interval: s.Interval().String(),
+		}
+	}
+
+	statuses := h.svc.ProviderStatus()
+	providers := make([]string, 0, len(statuses))
+	for _, st := range statuses {
+		providers = append(providers, st.Name)
+	}
+
+	lastFetch := h.store.LastFetchTimes()
+	return respond(c, fiber.Map{
+		"status":             "ok",
+		"providers":          providers,
+		"default_cities":     cfg.DefaultCities,
+		"fetch_interval":     cfg.FetchInterval.String(),
+		"openweathermap_key": cfg.OpenWeatherMapAPIKey != "",
+		"weatherapi_key":     cfg.WeatherAPIKey != "",
+		"request_timeout":    cfg.RequestTimeout.String(),
+		"default_timezone":   cfg.DefaultTimezone.String(),
+		"last_fetch":         lastFetch,
+		"scheduler_groups":   groups,
+		"freshness":          h.freshness(cfg.FreshnessSLAFactor, lastFetch),
+	})
+}
+
+// freshness checks each scheduled city's last fetch against factor times
+// its group interval, cities sorted by group and name. It returns nil if
+// factor is not positive.
+func (h *Handler) freshness(factor float64, lastFetch map[string]time.Time) *freshnessResponse {
+	if factor <= 0 {
+		return nil
+	}
+
+	now := h.clock.Now()
+	resp := &freshnessResponse{Fresh: true, Cities: []cityFreshResponse{}}
+	for name, s := range h.schedulers {
+		window := time.Duration(float64(s.Interval()) * factor)
+		for _, city := range s.Cities() {
+			entry := cityFreshResponse{City: city, Group: name, Window: window.String()}
+			if at, ok := lastFetch[weather.NormalizeCity(city)]; ok {
+				entry.LastFetch = &at
+				entry.Fresh = now.Sub(at) <= window
+			}
+			resp.Fresh = resp.Fresh && entry.Fresh
+			resp.Cities = append(resp.Cities, entry)
+		}
+	}
+	sort.Slice(resp.Cities, func(i, j int) bool {
+		a, b := resp.Cities[i], resp.Cities[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.City < b.City
+	})
+	return resp
+}
+
+// CurrentWeather handles GET /api/v1/weather/current?city=London.
+// Several comma-separated cities (city=London,Paris) return an array with
+// one entry per city instead of a single object.
+func (h *Handler) CurrentWeather(c *fiber.Ctx) error {
+	cities := config.ParseCities(c.Query("city"))
+	if len(cities) == 0 {
+		return h.writeError(c, fiber.StatusBadRequest, codeMissingCity, "city query parameter is required")
+	}
+	if len(cities) > maxBatchCities {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, "at most "+strconv.Itoa(maxBatchCities)+" cities per request")
+	}
+
+	fields, err := parseFields(c.Query("fields"), currentFields)
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidFields, err.Error())
+	}
+
+	naming, err := queryNaming(c.Query("naming"))
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+	project := withNaming(projectObject, naming)
+
+	refresh, err := queryBool(c, "refresh")
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+
+	lang, err := h.queryLanguage(c)
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+	ctx := weather.WithLanguage(requestContext(c), lang)
+
+	if len(cities) > 1 {
+		return h.currentBatch(ctx, c, cities, fields, project, refresh)
+	}
+
+	resp, lk, err := h.getCurrent(ctx, cities[0], refresh)
+	lk.record(c)
+	if err != nil {
+		return h.mapCityError(c, cities[0], err)
+	}
+
+	if resp.Partial && h.cfg.Current().PartialContentStatus {
+		c.Status(fiber.StatusPartialContent)
+	}
+
+	return sendProjected(c, resp, fields, project)
+}
+
+// currentBatch fetches current weather for several cities concurrently.
+// Per-city failures are reported in their entries; the response is 200.
+func (h *Handler) currentBatch(
+	ctx context.Context,
+	c *fiber.Ctx,
+	cities []string,
+	fields []string,
+	project func(any, []string) (any, error),
+	refresh bool,
+) error {
+	return h.sendBatch(c, cities, func(e *batchEntry) {
+		resp, _, err := h.getCurrent(ctx, e.City, refresh)
+		if err == nil {
+			e.Weather, err = project(resp, fields)
+		}
+		if err != nil {
+			e.fail(err)
+		}
+	})
+}
+
+// sendBatch runs fetch for every city and responds with the entries:
+// as one array by default, or as NDJSON, one entry per line written as
+// soon as it is ready, to clients accepting application/x-ndjson.
+// The array is sent with 207 Multi-Status instead of 200 when some cities
+// failed and others did not, if BATCH_MULTISTATUS is set; a stream has
+// sent its status before any city is done, so it is always 200.
+func (h *Handler) sendBatch(c *fiber.Ctx, cities []string, fetch func(e *batchEntry)) error {
+	if c.Accepts(fiber.MIMEApplicationJSON, mimeNDJSON) != mimeNDJSON {
+		entries := h.runBatch(cities, fetch)
+		if h.cfg.Current().BatchMultiStatus && mixedOutcome(entries) {
+			c.Status(fiber.StatusMultiStatus)
+		}
+		return respond(c, entries)
+	}
+
+	c.Set(fiber.HeaderContentType, mimeNDJSON)
+	// The writer runs after the handler returns; it must not touch c.
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		enc := json.NewEncoder(w)
+		h.streamBatch(cities, fetch, func(e batchEntry) {
+			if err := enc.Encode(e); err != nil {
+				h.log.Warn("failed to encode batch entry", "city", e.City, "error", err)
+				return
+			}
+			// A flush error means the client went away; the remaining
+			// fetches still finish and fill the cache.
+			_ = w.Flush()
+		})
+	})
+	return nil
+}
+
+// runBatch fills one entry per city using fetch, with at most
+// BATCH_CONCURRENCY cities in flight. Entries keep the order of cities
+// regardless of which finishes first, so clients can zip them with
+// their request.
+func (h *Handler) runBatch(cities []string, fetch func(e *batchEntry)) []batchEntry {
+	entries := make([]batchEntry, len(cities))
+	sem := make(chan struct{}, max(h.cfg.Current().BatchConcurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, city := range cities {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			entries[i] = batchEntry{City: city}
+			fetch(&entries[i])
+		})
+	}
+	wg.Wait()
+
+	return entries
+}
+
+// mixedOutcome reports whether some entries failed and others succeeded.
+func mixedOutcome(entries []batchEntry) bool {
+	failed := 0
+	for _, e := range entries {
+		if e.Status != 0 {
+			failed++
+		}
+	}
+	return failed > 0 && failed < len(entries)
+}
+
+// streamBatch is like runBatch but passes entries to emit as they
+// complete, in completion order. emit is called from the calling
+// goroutine only.
+func (h *Handler) streamBatch(cities []string, fetch func(e *batchEntry), emit func(batchEntry)) {
+	done := make(chan batchEntry)
+	sem := make(chan struct{}, max(h.cfg.Current().BatchConcurrency, 1))
+
+	var wg sync.WaitGroup
+	for _, city := range cities {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			e := batchEntry{City: city}
+			fetch(&e)
+			done <- e
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	for e := range done {
+		emit(e)
+	}
+}
+
+// getCurrent returns current weather from the fresh cache or the providers,
+// falling back to a stale cached value when all providers fail. With
+// refresh, a fresh cache entry is bypassed unless it is younger than
+// MIN_REFRESH_INTERVAL.
+// The cache holds descriptions in the default language, so a ctx asking
+// for another one (see queryLanguage) always calls the providers, and
+// the result is not cached.
+// The returned lookup describes the cache status and called providers.
+func (h *Handler) getCurrent(ctx context.Context, city string, refresh bool) (currentResponse, lookup, error) {
+	cfg := h.cfg.Current()
+	localized := weather.Language(ctx) != ""
+
+	// Try fresh cache first
+	entry, cached := h.store.GetCurrentEntry(city)
+	if cached && !localized {
+		if use, skipped := h.useCached(entry.At, refresh); use {
+			return currentResponse{CurrentWeather: entry.Data, FetchedAt: entry.At, RefreshSkipped: skipped}, lookup{cacheStatus: cacheHit}, nil
+		}
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx, cfg.RequestTimeout)
+	defer cancel()
+
+	w, report, err := h.svc.GetCurrentWeatherWithReport(ctxReq, city)
+	fetchedAt := h.clock.Now().UTC()
+	lk := lookup{cacheStatus: cacheMiss, report: &report}
+	if err != nil {
+		// Stale data beats an error when every provider is down.
+		if cached && h.serveStale(city, entry.At, err) {
+			h.log.Warn("serving stale current weather",
+				"city", city,
+				"fetched_at", entry.At,
+			)
+			lk.cacheStatus = cacheStale
+			return currentResponse{CurrentWeather: entry.Data, FetchedAt: entry.At, Stale: true}, lk, nil
+		}
+		return currentResponse{}, lk, err
+	}
+
+	partial := len(report.Failed) > 0
+	if localized {
+		return currentResponse{CurrentWeather: w, FetchedAt: fetchedAt, Partial: partial}, lk, nil
+	}
+
+	// Save to storage; the store stamps the fetch time and may keep a
+	// fresher cached observation.
+	w = h.store.SaveCurrent(city, w)
+
+	return currentResponse{CurrentWeather: w, FetchedAt: fetchedAt, Partial: partial}, lk, nil
+}
+
+// Forecast handles GET /api/v1/weather/forecast?city=London&days=1
+// and GET /api/v1/weather/forecast?city=London&hours=6. Without either,
+// DEFAULT_FORECAST_DAYS days are returned.
+// Timestamps are rendered in the `tz` zone, or DEFAULT_TIMEZONE if absent.
+// Several comma-separated cities return an array with one entry per city.
+func (h *Handler) Forecast(c *fiber.Ctx) error {
+	cities := config.ParseCities(c.Query("city"))
+	if len(cities) == 0 {
+		return h.writeError(c, fiber.StatusBadRequest, codeMissingCity, "city query parameter is required")
+	}
+	if len(cities) > maxBatchCities {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, "at most "+strconv.Itoa(maxBatchCities)+" cities per request")
+	}
+
+	rawDays := c.Query("days")
+	rawHours := c.Query("hours")
+
+	if rawDays != "" && rawHours != "" {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, "days and hours query parameters are mutually exclusive")
+	}
+
+	var (
+		days  int
+		hours int
+		err   error
+	)
+
+	switch {
+	case rawHours != "":
+		hours, err = strconv.Atoi(rawHours)
+		if err != nil {
+			return h.writeError(c, fiber.StatusBadRequest, codeInvalidHours, "invalid hours parameter, expected integer")
+		}
+		if hours < 1 || hours > maxForecastHours {
+			return h.writeError(c, fiber.StatusBadRequest, codeInvalidHours, "hours parameter must be in the 1 - 48 limit")
+		}
+		days = weather.DaysForHours(h.clock.Now(), hours)
+
+	case rawDays == "":
+		days = defaultDays(h.cfg.Current())
+
+	default:
+		days, err = strconv.Atoi(rawDays)
+		if err != nil {
+			return h.writeError(c, fiber.StatusBadRequest, codeInvalidDays, "invalid days parameter, expected integer")
+		}
+		var ok bool
+		if days, ok = h.checkDays(days); !ok {
+			return h.writeDaysError(c)
+		}
+	}
+
+	fields, err := parseFields(c.Query("fields"), forecastFields)
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidFields, err.Error())
+	}
+
+	naming, err := queryNaming(c.Query("naming"))
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+	project := withNaming(projectForecast, naming)
+
+	summary, err := queryBool(c, "summary")
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+
+	refresh, err := queryBool(c, "refresh")
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+
+	lang, err := h.queryLanguage(c)
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+	ctx := weather.WithLanguage(requestContext(c), lang)
+
+	loc := h.cfg.Current().DefaultTimezone
+	if tz := c.Query("tz"); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, "invalid tz parameter, expected IANA time zone name")
+		}
+	}
+
+	shape := forecastShape{hours: hours, summary: summary, loc: loc}
+
+	if len(cities) > 1 {
+		return h.sendBatch(c, cities, func(e *batchEntry) {
+			resp, _, err := h.getForecast(ctx, e.City, days, refresh)
+			if err == nil {
+				e.Forecast, err = project(h.shapeForecast(e.City, resp, shape), fields)
+			}
+			if err != nil {
+				e.fail(err)
+			}
+		})
+	}
+
+	resp, lk, err := h.getForecast(ctx, cities[0], days, refresh)
+	lk.record(c)
+	if err != nil {
+		return h.mapCityError(c, cities[0], err)
+	}
+
+	return sendProjected(c, h.shapeForecast(cities[0], resp, shape), fields, project)
+}
+
+// forecastShape holds the response options of a forecast request.
+type forecastShape struct {
+	hours   int // 0 keeps all items
+	summary bool
+	loc     *time.Location
+}
+
+// shapeForecast applies the hours window, daily summary and time zone
+// to a forecast response for city.
+func (h *Handler) shapeForecast(city string, resp forecastResponse, shape forecastShape) forecastResponse {
+	if shape.hours > 0 {
+		resp.Items = weather.NextHours(resp.Items, h.clock.Now(), shape.hours)
+		resp.ActualDays = weather.DistinctDays(resp.Items)
+	}
+
+	// Daily buckets use UTC days, like the rest of the stored data.
+	// Scheduled cities have them precomputed unless items were cut by hours.
+	if shape.summary {
+		var ok bool
+		if shape.hours == 0 && h.aggregates != nil {
+			resp.Daily, ok = h.aggregates.Daily(city, resp.Forecast)
+		}
+		if !ok {
+			resp.Daily = weather.DailySummaries(resp.Items)
+		}
+	}
+
+	// Data is kept in UTC; convert only for the response.
+	resp.Forecast = resp.Forecast.In(shape.loc)
+
+	return resp
+}
+
+// getForecast returns a forecast from the fresh cache or the providers,
+// falling back to a stale cached value when all providers fail.
+// refresh and the language in ctx have the same meaning as in getCurrent.
+// The returned lookup describes the cache status and called providers.
+func (h *Handler) getForecast(ctx context.Context, city string, days int, refresh bool) (forecastResponse, lookup, error) {
+	cfg := h.cfg.Current()
+	localized := weather.Language(ctx) != ""
+
+	// Try fresh cache first
+	entry, cached := h.cachedForecast(city, days, cfg.CacheTTL)
+	if cached && !localized {
+		if use, skipped := h.useCached(entry.At, refresh); use {
+			return forecastResponse{Forecast: weather.FirstDays(entry.Data, days, h.clock.Now()), FetchedAt: entry.At, RefreshSkipped: skipped}, lookup{cacheStatus: cacheHit}, nil
+		}
+	}
+
+	ctxReq, cancel := context.WithTimeout(ctx, cfg.RequestTimeout)
+	defer cancel()
+
+	// Refresh the canonical (longest) entry rather than adding a new key.
+	fetchDays := days
+	if cached {
+		fetchDays = entry.Days
+	}
+
+	fc, report, err := h.svc.GetForecastWithReport(ctxReq, city, fetchDays)
+	fetchedAt := h.clock.Now().UTC()
+	lk := lookup{cacheStatus: cacheMiss, report: &report}
+	if err != nil {
+		if cached && h.serveStale(city, entry.At, err) {
+			h.log.Warn("serving stale forecast",
+				"city", city,
+				"days", days,
+				"fetched_at", entry.At,
+			)
+			lk.cacheStatus = cacheStale
+			return forecastResponse{Forecast: weather.FirstDays(entry.Data, days, h.clock.Now()), FetchedAt: entry.At, Stale: true}, lk, nil
+		}
+		return forecastResponse{}, lk, err
+	}
+
+	if !localized {
+		h.store.SaveForecast(city, fetchDays, fc)
+	}
+
+	return forecastResponse{Forecast: weather.FirstDays(fc, days, h.clock.Now()), FetchedAt: fetchedAt}, lk, nil
+}
+
+// serveStale reports whether a cached entry for city fetched at fetchedAt
+// may be served, marked stale, after a live fetch failed with err. That
+// requires SERVE_STALE_ON_FAILURE, a temporary failure, and an entry no
+// older than STALE_MAX_AGE (if set).
+func (h *Handler) serveStale(city string, fetchedAt time.Time, err error) bool {
+	cfg := h.cfg.Current()
+	if !cfg.ServeStaleOnFailure || !isTemporary(err) {
+		return false
+	}
+
+	if age := h.clock.Now().Sub(fetchedAt); cfg.StaleMaxAge > 0 && age > cfg.StaleMaxAge {
+		h.log.Warn("cached data too old to serve stale",
+			"city", city,
+			"fetched_at", fetchedAt,
+			"stale_max_age", cfg.StaleMaxAge.String(),
+		)
+		return false
+	}
+	return true
+}
+
+// useCached reports whether an entry fetched at fetchedAt should be served
+// instead of calling the providers. A refresh is honored only for entries
+// at least MIN_REFRESH_INTERVAL old; otherwise skipped is true.
+func (h *Handler) useCached(fetchedAt time.Time, refresh bool) (use, skipped bool) {
+	cfg := h.cfg.Current()
+	age := h.clock.Now().Sub(fetchedAt)
+
+	if age > cfg.CacheTTL {
+		return false, false
+	}
+	if !refresh {
+		return true, false
+	}
+	if age < cfg.MinRefreshInterval {
+		return true, true
+	}
+	return false, false
+}
+
+// cachedForecast returns the cached forecast covering at least days days.
+// Among such entries a fresh one wins, then the longest one.
+func (h *Handler) cachedForecast(city string, days int, ttl time.Duration) (storage.ForecastSnapshot, bool) {
+	keys := make([]int, 0)
+	for d := range h.store.Inspect(city).Forecasts {
+		if d >= days {
+			keys = append(keys, d)
+		}
+	}
+	if len(keys) == 0 {
+		return storage.ForecastSnapshot{}, false
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+
+	var longest storage.ForecastSnapshot
+	for i, d := range keys {
+		entry, ok := h.store.GetForecastEntry(city, d)
+		if !ok {
+			continue
+		}
+		if h.clock.Now().Sub(entry.At) <= ttl {
+			return entry, true
+		}
+		if i == 0 {
+			longest = entry
+		}
+	}
+	return longest, longest.Days > 0
+}
+
+// checkDays validates a forecast days value against the configured range.
+// In lenient mode out-of-range values are clamped instead of rejected.
+func (h *Handler) checkDays(days int) (int, bool) {
+	cfg := h.cfg.Current()
+	if days >= cfg.MinForecastDays && days <= cfg.MaxForecastDays {
+		return days, true
+	}
+	if cfg.DaysValidation == daysValidationLenient {
+		return min(max(days, cfg.MinForecastDays), cfg.MaxForecastDays), true
+	}
+	return days, false
+}
+
+// defaultDays returns DEFAULT_FORECAST_DAYS clamped to the allowed range,
+// the days of forecast requests that do not ask for any.
+func defaultDays(cfg *config.Config) int {
+	return min(max(cfg.DefaultForecastDays, cfg.MinForecastDays), cfg.MaxForecastDays)
+}
+
+func (h *Handler) writeDaysError(c *fiber.Ctx) error {
+	cfg := h.cfg.Current()
+	return h.writeError(c, fiber.StatusBadRequest, codeInvalidDays, fmt.Sprintf("days parameter must be in the %d - %d limit",
+		cfg.MinForecastDays, cfg.MaxForecastDays))
+}
+
+// Prefetch handles POST /api/v1/weather/prefetch?city=London&days=3.
+// It queues a background fetch of current weather and forecast and
+// returns 202 without waiting for it. Without days, the forecast a
+// default /forecast request reads is warmed.
+func (h *Handler) Prefetch(c *fiber.Ctx) error {
+	city := c.Query("city")
+	if city == "" {
+		return h.writeError(c, fiber.StatusBadRequest, codeMissingCity, "city query parameter is required")
+	}
+
+	cfg := h.cfg.Current()
+	days := defaultDays(cfg)
+	if raw := c.Query("days"); raw != "" {
+		var err error
+		days, err = strconv.Atoi(raw)
+		if err != nil {
+			return h.writeError(c, fiber.StatusBadRequest, codeInvalidDays, "invalid days parameter, expected integer")
+		}
+	}
+	var ok bool
+	if days, ok = h.checkDays(days); !ok {
+		return h.writeDaysError(c)
+	}
+
+	queued, err := h.prefetcher.Enqueue(city, days)
+	if err != nil {
+		setRetryAfter(c, cfg.RetryAfter)
+		return h.writeError(c, fiber.StatusServiceUnavailable, codePrefetchQueueFull, "prefetch queue is full")
+	}
+
+	status := "queued"
+	if !queued {
+		status = "pending"
+	}
+
+	return respond(c.Status(fiber.StatusAccepted), fiber.Map{
+		"city":   city,
+		"days":   days,
+		"status": status,
+	})
+}
+
+// trendResponse is the payload of the trend endpoint.
+type trendResponse struct {
+	City         string              `json:"city"`
+	Points       int                 `json:"points"`
+	SlopePerHour float64             `json:"slope_per_hour"` // Celsius per hour
+	Direction    analytics.Direction `json:"direction"`
+	Deltas       []trendDelta        `json:"deltas"`
+}
+
+type trendDelta struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Change float64   `json:"change"`
+}
+
+// Trend handles GET /api/v1/weather/trend?city=London&limit=20 and reports
+// how the temperature changed over the stored current weather history.
+func (h *Handler) Trend(c *fiber.Ctx) error {
+	city := c.Query("city")
+	if city == "" {
+		return h.writeError(c, fiber.StatusBadRequest, codeMissingCity, "city query parameter is required")
+	}
+
+	limit, err := h.historyLimit(c)
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, err.Error())
+	}
+
+	trend, err := analytics.TemperatureTrend(h.store.CurrentHistory(city, limit))
+	if err != nil {
+		return h.writeError(c, fiber.StatusNotFound, codeInsufficientHistory, "at least two stored observations are required")
+	}
+
+	resp := trendResponse{
+		City:         city,
+		Points:       trend.Points,
+		SlopePerHour: trend.SlopePerHour,
+		Direction:    trend.Direction,
+		Deltas:       make([]trendDelta, 0, len(trend.Deltas)),
+	}
+	for _, d := range trend.Deltas {
+		resp.Deltas = append(resp.Deltas, trendDelta{From: d.From, To: d.To, Change: d.Change})
+	}
+
+	return respond(c, resp)
+}
+
+// historyLimit parses the optional `limit` query parameter of history
+// reads. It defaults to, and may not exceed, MAX_HISTORY_LIMIT, so a
+// store retaining long histories cannot be asked for all of them.
+func (h *Handler) historyLimit(c *fiber.Ctx) (int, error) {
+	maxLimit := max(h.cfg.Current().MaxHistoryLimit, 1)
+
+	raw := c.Query("limit")
+	if raw == "" {
+		return maxLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, errors.New("invalid limit parameter, expected positive integer")
+	}
+	if limit > maxLimit {
+		return 0, fmt.Errorf("limit parameter must be at most %d", maxLimit)
+	}
+	return limit, nil
+}
+
+// Cities handles GET /api/v1/cities and lists every city present in the
+// store, marking the ones kept warm by a scheduler group.
+func (h *Handler) Cities(c *fiber.Ctx) error {
+	scheduled := make(map[string]bool)
+	for _, s := range h.schedulers {
+		for _, city := range s.Cities() {
+			scheduled[weather.NormalizeCity(city)] = true
+		}
+	}
+
+	lastFetch := h.store.LastFetchTimes()
+	cities := h.store.Cities()
+
+	resp := make([]cityResponse, 0, len(cities))
+	for _, city := range cities {
+		key := weather.NormalizeCity(city)
+		resp = append(resp, cityResponse{
+			Name:      city,
+			LastFetch: lastFetch[key],
+			Scheduled: scheduled[key],
+		})
+	}
+
+	return respond(c, fiber.Map{"cities": resp})
+}
+
+// CacheInfo handles GET /api/v1/cache?city=London and reports what is
+// cached for the city, with ages but without payloads.
+func (h *Handler) CacheInfo(c *fiber.Ctx) error {
+	city := c.Query("city")
+	if city == "" {
+		return h.writeError(c, fiber.StatusBadRequest, codeMissingCity, "city query parameter is required")
+	}
+
+	info := h.store.Inspect(city)
+	now := h.clock.Now()
+
+	resp := cacheResponse{
+		City:      cmp.Or(info.City, city),
+		Forecasts: make([]cacheEntryResponse, 0, len(info.Forecasts)),
+	}
+
+	if info.Current != nil {
+		resp.Current = &cacheEntryResponse{
+			FetchedAt: *info.Current,
+			Age:       now.Sub(*info.Current).Round(time.Second).String(),
+		}
+	}
+
+	for days, at := range info.Forecasts {
+		resp.Forecasts = append(resp.Forecasts, cacheEntryResponse{
+			Days:      days,
+			FetchedAt: at,
+			Age:       now.Sub(at).Round(time.Second).String(),
+		})
+	}
+	sort.Slice(resp.Forecasts, func(i, j int) bool {
+		return resp.Forecasts[i].Days < resp.Forecasts[j].Days
+	})
+
+	return respond(c, resp)
+}
+
+// EvictCache handles DELETE /api/v1/admin/cache?city=London and removes
+// everything cached for the city.
+func (h *Handler) EvictCache(c *fiber.Ctx) error {
+	city := c.Query("city")
+	if city == "" {
+		return h.writeError(c, fiber.StatusBadRequest, codeMissingCity, "city query parameter is required")
+	}
+
+	evicted := h.store.Evict(city)
+	h.log.Info("cache evicted",
+		"city", city,
+		"found", evicted,
+	)
+
+	return respond(c, fiber.Map{
+		"city":    city,
+		"evicted": evicted,
+	})
+}
+
+// ExportStore handles GET /api/v1/admin/export and returns the whole
+// store, history included, as one snapshot document for backups. Like
+// every endpoint it answers XML on request, but only the JSON document
+// can be restored with ImportStore.
+func (h *Handler) ExportStore(c *fiber.Ctx) error {
+	snap := h.store.Export()
+	h.log.Info("store exported", "cities", len(snap.Cities))
+	return respond(c, snap)
+}
+
+// ImportStore handles POST /api/v1/admin/import and restores a snapshot
+// produced by ExportStore, replacing the data of the cities it contains.
+// Malformed entries are dropped and counted in the response.
+func (h *Handler) ImportStore(c *fiber.Ctx) error {
+	var snap storage.Snapshot
+	if err := json.Unmarshal(c.Body(), &snap); err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeBadRequest, "invalid snapshot: "+err.Error())
+	}
+
+	res, err := h.store.Import(snap)
+	if err != nil {
+		return h.writeError(c, fiber.StatusBadRequest, codeBadRequest, err.Error())
+	}
+	h.log.Info("store imported",
+		"cities", res.Cities,
+		"current", res.Current,
+		"forecasts", res.Forecasts,
+		"dropped", res.Dropped,
+	)
+
+	return respond(c, res)
+}
+
+// ReleaseQuarantine handles DELETE /api/v1/admin/scheduler/quarantine?city=London
+// and makes every scheduler group fetch the city again.
+func (h *Handler) ReleaseQuarantine(c *fiber.Ctx) error {
+	city := c.Query("city")
+	if city == "" {
+		return h.writeError(c, fiber.StatusBadRequest, codeMissingCity, "city query parameter is required")
+	}
+
+	released := false
+	for _, s := range h.schedulers {
+		if s.Release(city) {
+			released = true
+		}
+	}
+
+	return respond(c, fiber.Map{
+		"city":     city,
+		"released": released,
+	})
+}
+
+// SweepRetention handles POST /api/v1/admin/retention/sweep. It deletes
+// data fetched longer than older_than ago, RETENTION_PERIOD by default,
+// without waiting for the background sweep.
+func (h *Handler) SweepRetention(c *fiber.Ctx) error {
+	period := h.cfg.Current().RetentionPeriod
+	if raw := c.Query("older_than"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, "invalid older_than parameter, expected a positive duration such as 168h")
+		}
+		period = d
+	}
+	if period <= 0 {
+		return h.writeError(c, fiber.StatusBadRequest, codeInvalidParameter, "older_than query parameter is required when RETENTION_PERIOD is not set")
+	}
+
+	removed, cutoff := storage.SweepOlderThan(h.store, period, h.clock)
+	h.log.Info("retention sweep triggered",
+		"removed", removed,
+		"cutoff", cutoff,
+	)
+
+	return respond(c, fiber.Map{
+		"removed": removed,
+		"cutoff":  cutoff,
+	})
+}
+
+// queryLanguage parses the optional `lang` query parameter. It returns ""
+// when absent or equal to the service's default language, so only other
+// languages reach the context.
+func (h *Handler) queryLanguage(c *fiber.Ctx) (string, error) {
+	raw := c.Query("lang")
+	if raw == "" {
+		return "", nil
+	}
+	lang, err := weather.ParseLanguage(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid lang parameter: %w", err)
+	}
+	if lang == h.svc.Language() {
+		return "", nil
+	}
+	return lang, nil
+}
+
+// queryBool parses an optional boolean query parameter; absent means false.
+func queryBool(c *fiber.Ctx, key string) (bool, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter, expected boolean", key)
+	}
+	return b, nil
+}
+
+// sendProjected writes v as JSON after applying the field projection.
+func sendProjected(c *fiber.Ctx, v any, fields []string, project func(any, []string) (any, error)) error {
+	out, err := project(v, fields)
+	if err != nil {
+		return err
+	}
+	return respond(c, out)
+}
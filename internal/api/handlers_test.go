@@ -0,0 +1,227 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/config"
+	"github.com/andrqxa/weather-aggregator/internal/scheduler"
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRunBatchKeepsRequestOrder(t *testing.T) {
+	h := &Handler{cfg: config.NewHolder(&config.Config{BatchConcurrency: 4})}
+	cities := []string{"London", "Paris", "Berlin", "Madrid", "Rome", "Vienna", "Oslo", "Prague"}
+
+	for range 5 {
+		entries := h.runBatch(cities, func(e *batchEntry) {
+			// Randomized delays make completion order differ from request order.
+			time.Sleep(rand.N(3 * time.Millisecond))
+			e.Weather = e.City
+		})
+
+		if len(entries) != len(cities) {
+			t.Fatalf("got %d entries, want %d", len(entries), len(cities))
+		}
+		for i, e := range entries {
+			if e.City != cities[i] || e.Weather != cities[i] {
+				t.Fatalf("entry %d = %+v, want city %s", i, e, cities[i])
+			}
+		}
+	}
+}
+
+func TestExportStoreNegotiatesFormat(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := storage.NewInMemoryStore(clk)
+	store.SaveCurrent("London", weather.CurrentWeather{City: "London", Temperature: 8, ObservedAt: clk.Now()})
+	h := NewHandler(config.NewHolder(&config.Config{}), nil, store, nil, nil, nil, nil, clk,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	app := fiber.New()
+	app.Get("/export", h.ExportStore)
+
+	tests := []struct {
+		accept      string
+		contentType string
+		want        string
+	}{
+		{"", fiber.MIMEApplicationJSON, `"city":"London"`},
+		{fiber.MIMEApplicationXML, fiber.MIMEApplicationXMLCharsetUTF8, "<city>London</city>"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodGet, "/export", nil)
+		if tt.accept != "" {
+			req.Header.Set(fiber.HeaderAccept, tt.accept)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+
+		if got := resp.Header.Get(fiber.HeaderContentType); got != tt.contentType {
+			t.Errorf("Accept %q: Content-Type = %q, want %q", tt.accept, got, tt.contentType)
+		}
+		if !strings.Contains(string(body), tt.want) {
+			t.Errorf("Accept %q: body %s does not contain %s", tt.accept, body, tt.want)
+		}
+	}
+}
+
+func TestPrefetchDefaultsAndQueueFull(t *testing.T) {
+	cfg := config.NewHolder(&config.Config{
+		MinForecastDays:     1,
+		MaxForecastDays:     7,
+		DefaultForecastDays: 5,
+		RetryAfter:          1500 * time.Millisecond,
+	})
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Workers are not started, so the single queue slot stays taken.
+	prefetcher := scheduler.NewPrefetcher(nil, nil, time.Second, 1, 1, log)
+	h := NewHandler(cfg, nil, nil, nil, prefetcher, nil, nil, nil, log)
+
+	app := fiber.New()
+	app.Post("/prefetch", h.Prefetch)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/prefetch?city=London", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Days int `json:"days"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusAccepted || body.Days != 5 {
+		t.Errorf("got %d with days %d, want 202 with the default 5 days", resp.StatusCode, body.Days)
+	}
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodPost, "/prefetch?city=Paris", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+	if got := resp.Header.Get(fiber.HeaderRetryAfter); got != "2" {
+		t.Errorf("Retry-After = %q, want 1.5s rounded up to 2", got)
+	}
+}
+
+func TestCurrentWeatherFromCacheKeepsObservedAt(t *testing.T) {
+	stored := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	observed := stored.Add(-20 * time.Minute)
+	clk := clock.NewManual(stored)
+	store := storage.NewInMemoryStore(clk)
+	store.SaveCurrent("London", weather.CurrentWeather{City: "London", Temperature: 8, ObservedAt: observed})
+	clk.Advance(5 * time.Minute)
+
+	cfg := config.NewHolder(&config.Config{CacheTTL: 15 * time.Minute})
+	h := NewHandler(cfg, nil, store, nil, nil, nil, nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	app := fiber.New()
+	app.Get("/current", h.CurrentWeather)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/current?city=London", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		ObservedAt time.Time `json:"observed_at"`
+		FetchedAt  time.Time `json:"fetched_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if !body.ObservedAt.Equal(observed) {
+		t.Errorf("observed_at = %v, want the provider's %v", body.ObservedAt, observed)
+	}
+	if !body.FetchedAt.Equal(stored) {
+		t.Errorf("fetched_at = %v, want the store time %v", body.FetchedAt, stored)
+	}
+}
+
+func TestForecastTrimmedFromLongerCacheEntry(t *testing.T) {
+	stored := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	updated := stored.Add(-30 * time.Minute)
+	clk := clock.NewManual(stored)
+
+	today := stored.Truncate(24 * time.Hour)
+	items := make([]weather.ForecastItem, 0, 7*24)
+	for i := range 7 * 24 {
+		items = append(items, weather.ForecastItem{TimeStamp: today.Add(time.Duration(i) * time.Hour), Temperature: 8})
+	}
+	store := storage.NewInMemoryStore(clk)
+	store.SaveForecast("London", 7, weather.Forecast{City: "London", Items: items, Days: 7, ActualDays: 7, UpdatedAt: updated})
+
+	// Every provider is down, so an expired entry is served stale.
+	svc := weather.NewService([]weather.Provider{&downProvider{name: "openmeteo"}}, clk)
+	cfg := config.NewHolder(&config.Config{
+		CacheTTL:            15 * time.Minute,
+		RequestTimeout:      time.Second,
+		MinForecastDays:     1,
+		MaxForecastDays:     7,
+		DefaultTimezone:     time.UTC,
+		ServeStaleOnFailure: true,
+	})
+	h := NewHandler(cfg, svc, store, nil, nil, nil, nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	app := fiber.New()
+	app.Get("/forecast", h.Forecast)
+
+	type forecast struct {
+		Days       int       `json:"days"`
+		ActualDays int       `json:"actual_days"`
+		UpdatedAt  time.Time `json:"updated_at"`
+		FetchedAt  time.Time `json:"fetched_at"`
+		Stale      bool      `json:"stale"`
+	}
+	get := func() forecast {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/forecast?city=London&days=3", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+		var body forecast
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	clk.Advance(10 * time.Minute)
+	body := get()
+	if body.Days != 3 || body.ActualDays != 3 || body.Stale {
+		t.Errorf("got %+v, want a fresh 3 days", body)
+	}
+	if !body.UpdatedAt.Equal(updated) || !body.FetchedAt.Equal(stored) {
+		t.Errorf("updated_at %v, fetched_at %v, want the cached %v and %v", body.UpdatedAt, body.FetchedAt, updated, stored)
+	}
+
+	// The trimmed view is not cached on its own, so the TTL still runs
+	// from when the 7 days were stored.
+	if _, ok := store.Inspect("London").Forecasts[3]; ok {
+		t.Error("the 3 day view was cached")
+	}
+	clk.Advance(6 * time.Minute)
+	body = get()
+	if !body.Stale || !body.FetchedAt.Equal(stored) || !body.UpdatedAt.Equal(updated) {
+		t.Errorf("got %+v, want the 7 day entry served stale", body)
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram: from cache hits in a few milliseconds to fan-outs waiting on
+// REQUEST_TIMEOUT.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// cacheNone labels requests that did not go through the cache, e.g.
+// batch requests or ones rejected before the lookup.
+const cacheNone = "none"
+
+type latencyLabels struct {
+	route string
+	cache string
+}
+
+type latencySeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// latencyHistogram is a Prometheus-style histogram of request durations
+// labelled by route and cache status.
+type latencyHistogram struct {
+	mu     sync.Mutex
+	series map[latencyLabels]*latencySeries
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{series: make(map[latencyLabels]*latencySeries)}
+}
+
+func (h *latencyHistogram) observe(labels latencyLabels, d time.Duration) {
+	v := d.Seconds()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[labels]
+	if !ok {
+		s = &latencySeries{counts: make([]uint64, len(latencyBuckets))}
+		h.series[labels] = s
+	}
+	if i, _ := slices.BinarySearch(latencyBuckets, v); i < len(latencyBuckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// writeTo writes the histogram in the Prometheus text exposition format,
+// series sorted by labels.
+func (h *latencyHistogram) writeTo(b *strings.Builder) {
+	const name = "weather_http_request_duration_seconds"
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	keys := make([]latencyLabels, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b latencyLabels) int {
+		return strings.Compare(a.route+"\x00"+a.cache, b.route+"\x00"+b.cache)
+	})
+
+	fmt.Fprintf(b, "# HELP %s Weather API request latency by route and cache status.\n", name)
+	fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+	for _, k := range keys {
+		s := h.series[k]
+		labels := fmt.Sprintf("route=%q,cache=%q", k.route, k.cache)
+
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket{%s,le=%q} %d\n", name, labels, strconv.FormatFloat(le, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, s.count)
+		fmt.Fprintf(b, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(s.sum, 'g', -1, 64))
+		fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, s.count)
+	}
+}
+
+// recordLatency records the duration of weather requests in the latency
+// histogram, labelled by the matched route and the cache status the
+// handler set (see setCacheStatus).
+func (h *Handler) recordLatency(c *fiber.Ctx) error {
+	start := time.Now()
+	err := c.Next()
+
+	cache, ok := c.Locals(localCacheStatus).(string)
+	if !ok {
+		cache = cacheNone
+	}
+	// The route pattern, not the path, keeps label cardinality bounded.
+	h.latency.observe(latencyLabels{route: c.Route().Path, cache: cache}, time.Since(start))
+
+	return err
+}
+
+// Metrics handles GET /metrics, exposing the request latency histogram in
+// the Prometheus text format.
+func (h *Handler) Metrics(c *fiber.Ctx) error {
+	var b strings.Builder
+	h.latency.writeTo(&b)
+
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return c.SendString(b.String())
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Cache statuses recorded by handlers for the access log.
+const (
+	cacheHit   = "hit"
+	cacheMiss  = "miss"
+	cacheStale = "stale"
+)
+
+// Keys of request-scoped values shared between handlers and middleware.
+const (
+	localCacheStatus = "cache_status"
+	localProviders   = "providers"
+	localRequestID   = "request_id"
+)
+
+func setCacheStatus(c *fiber.Ctx, status string) {
+	c.Locals(localCacheStatus, status)
+}
+
+func setProviders(c *fiber.Ctx, report weather.Report) {
+	c.Locals(localProviders, report)
+}
+
+// RequestID makes sure every request has a correlation ID: the value of
+// header if the client sent one, a generated one otherwise. The ID is
+// echoed in the response and passed on to providers via requestContext.
+// An empty header disables it.
+func RequestID(header string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if header == "" {
+			return c.Next()
+		}
+
+		// Fiber reuses header buffers once the handler returns; copy it.
+		id := strings.Clone(c.Get(header))
+		if id == "" {
+			id = rand.Text()
+		}
+		c.Locals(localRequestID, id)
+		c.Set(header, id)
+
+		return c.Next()
+	}
+}
+
+// requireAdmin guards admin routes. Requests must carry ADMIN_API_KEY as
+// a bearer token; without a configured key the admin API is disabled.
+func (h *Handler) requireAdmin(c *fiber.Ctx) error {
+	key := h.cfg.Current().AdminAPIKey
+	if key == "" {
+		return h.writeError(c, fiber.StatusForbidden, codeForbidden, "admin API is disabled, set ADMIN_API_KEY to enable it")
+	}
+
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+		return h.writeError(c, fiber.StatusUnauthorized, codeUnauthorized, "missing or invalid admin API key")
+	}
+
+	return c.Next()
+}
+
+// requestContext returns a background context carrying the request's
+// correlation ID, for calls that should not be cut short by the client.
+func requestContext(c *fiber.Ctx) context.Context {
+	ctx := context.Background()
+	if id, ok := c.Locals(localRequestID).(string); ok {
+		ctx = weather.WithRequestID(ctx, id)
+	}
+	return ctx
+}
+
+// AccessLog logs one structured line per weather request, including the
+// domain context the generic Fiber logger does not know about: city,
+// cache status and the providers that were called.
+func AccessLog(log *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		attrs := []any{
+			"method", c.Method(),
+			"endpoint", c.Path(),
+			"city", c.Query("city"),
+			"status", c.Response().StatusCode(),
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+		}
+
+		if id, ok := c.Locals(localRequestID).(string); ok {
+			attrs = append(attrs, "request_id", id)
+		}
+		if status, ok := c.Locals(localCacheStatus).(string); ok {
+			attrs = append(attrs, "cache_hit", status == cacheHit, "cache_status", status)
+		}
+		if report, ok := c.Locals(localProviders).(weather.Report); ok {
+			attrs = append(attrs,
+				"providers_called", report.Called,
+				"providers_succeeded", report.Succeeded,
+				"providers_failed", report.Failed,
+			)
+		}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		}
+
+		log.Info("weather request", attrs...)
+		return err
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Response field naming conventions selected by the `naming` query
+// parameter. Field names are snake_case by default; camelCase is offered
+// to ease client migrations.
+const (
+	namingSnake = "snake"
+	namingCamel = "camel"
+)
+
+// queryNaming parses the optional `naming` query parameter.
+func queryNaming(raw string) (string, error) {
+	switch raw {
+	case "", namingSnake:
+		return namingSnake, nil
+	case namingCamel:
+		return namingCamel, nil
+	default:
+		return "", errors.New("invalid naming parameter, expected snake or camel")
+	}
+}
+
+// withNaming wraps a projection so its result uses the naming convention.
+// Projections select fields by their snake_case names either way.
+func withNaming(project func(any, []string) (any, error), naming string) func(any, []string) (any, error) {
+	if naming != namingCamel {
+		return project
+	}
+	return func(v any, fields []string) (any, error) {
+		out, err := project(v, fields)
+		if err != nil {
+			return nil, err
+		}
+		return camelKeys(out)
+	}
+}
+
+// camelKeys returns v as generic JSON with every object key, at any
+// depth, converted to camelCase.
+func camelKeys(v any) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	return renameKeys(generic, snakeToCamel), nil
+}
+
+func renameKeys(v any, rename func(string) string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(v))
+		for k, val := range v {
+			res[rename(k)] = renameKeys(val, rename)
+		}
+		return res
+	case []any:
+		for i, val := range v {
+			v[i] = renameKeys(val, rename)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// snakeToCamel converts a snake_case name to camelCase, e.g. wind_speed
+// to windSpeed.
+func snakeToCamel(s string) string {
+	first, rest, found := strings.Cut(s, "_")
+	if !found {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(first)
+	for part := range strings.SplitSeq(rest, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
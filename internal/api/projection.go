@@ -0,0 +1,129 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+// Field sets accepted by the `fields` query parameter.
+var (
+	currentFields  = jsonFieldNames(reflect.TypeFor[currentResponse]())
+	forecastFields = jsonFieldNames(reflect.TypeFor[weather.ForecastItem]())
+)
+
+// parseFields parses a comma-separated `fields` query value and validates
+// each name against allowed. An empty value means "all fields".
+func parseFields(raw string, allowed map[string]bool) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(raw, ",")
+	fields := make([]string, 0, len(parts))
+
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !allowed[p] {
+			return nil, fmt.Errorf("unknown field %q", p)
+		}
+		fields = append(fields, p)
+	}
+
+	return fields, nil
+}
+
+// projectObject returns v as a JSON object containing only the given fields.
+// If fields is empty, v is returned unchanged.
+func projectObject(v any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+
+	m, err := toJSONMap(v)
+	if err != nil {
+		return nil, err
+	}
+	return filterKeys(m, fields), nil
+}
+
+// projectForecast returns v as a JSON object whose forecast items contain
+// only the given fields; top-level forecast fields are kept as is.
+// If fields is empty, v is returned unchanged.
+func projectForecast(v any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+
+	m, err := toJSONMap(v)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := m["items"].([]any)
+	for i, it := range items {
+		if obj, ok := it.(map[string]any); ok {
+			items[i] = filterKeys(obj, fields)
+		}
+	}
+
+	return m, nil
+}
+
+func toJSONMap(v any) (map[string]any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func filterKeys(m map[string]any, fields []string) map[string]any {
+	res := make(map[string]any, len(fields))
+	for _, f := range fields {
+		if v, ok := m[f]; ok {
+			res[f] = v
+		}
+	}
+	return res
+}
+
+// jsonFieldNames returns the JSON field names of a struct type,
+// including fields promoted from embedded structs.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			for name := range jsonFieldNames(f.Type) {
+				names[name] = true
+			}
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[name] = true
+	}
+
+	return names
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// readiness caches the last provider probe, so frequent readiness checks
+// do not spend provider quota.
+type readiness struct {
+	mu        sync.Mutex // also serializes probes
+	checkedAt time.Time
+	reachable []string // providers that answered the last probe
+}
+
+// readyResponse is the /readyz payload.
+type readyResponse struct {
+	Status    string    `json:"status"`
+	Reachable []string  `json:"reachable"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Readyz handles GET /readyz. The instance is ready while at least one
+// provider answered a current weather request for READINESS_PROBE_CITY
+// within the last READINESS_PROBE_WINDOW; otherwise it answers 503, so
+// traffic is not routed to an instance whose providers are unreachable.
+func (h *Handler) Readyz(c *fiber.Ctx) error {
+	cfg := h.cfg.Current()
+	now := h.clock.Now()
+
+	h.ready.mu.Lock()
+	defer h.ready.mu.Unlock()
+
+	if h.ready.checkedAt.IsZero() || now.Sub(h.ready.checkedAt) >= cfg.ReadinessWindow {
+		ctx, cancel := context.WithTimeout(c.UserContext(), cfg.RequestTimeout)
+		defer cancel()
+
+		reachable := []string{}
+		for _, r := range h.svc.Probe(ctx, cfg.ReadinessProbeCity) {
+			if r.Err != nil {
+				h.log.Warn("readiness probe failed",
+					"provider", r.Provider,
+					"city", cfg.ReadinessProbeCity,
+					"error", r.Err,
+				)
+				continue
+			}
+			reachable = append(reachable, r.Provider)
+		}
+		h.ready.checkedAt = now.UTC()
+		h.ready.reachable = reachable
+	}
+
+	resp := readyResponse{
+		Status:    "ready",
+		Reachable: h.ready.reachable,
+		CheckedAt: h.ready.checkedAt,
+	}
+	if len(resp.Reachable) == 0 {
+		resp.Status = "not ready"
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return respond(c, resp)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/config"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+// downProvider fails every request, counting them.
+type downProvider struct {
+	name  string
+	calls atomic.Int32
+}
+
+func (p *downProvider) Name() string { return p.name }
+
+func (p *downProvider) FetchCurrent(context.Context, string) (weather.CurrentWeather, error) {
+	p.calls.Add(1)
+	return weather.CurrentWeather{}, weather.ErrProviderUnavailable
+}
+
+func (p *downProvider) FetchForecast(context.Context, string, int) (weather.Forecast, error) {
+	return weather.Forecast{}, weather.ErrProviderUnavailable
+}
+
+func TestReadyzNotReadyWhenAllProvidersFail(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	providers := []*downProvider{{name: "openmeteo"}, {name: "nws"}}
+	svc := weather.NewService([]weather.Provider{providers[0], providers[1]}, clk)
+	cfg := config.NewHolder(&config.Config{
+		RequestTimeout:     time.Second,
+		ReadinessProbeCity: "London",
+		ReadinessWindow:    30 * time.Second,
+	})
+	h := NewHandler(cfg, svc, nil, nil, nil, nil, nil, clk, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	app := fiber.New()
+	app.Get("/readyz", h.Readyz)
+
+	// The second check is answered from the cached probe, the third
+	// probes again once the window has passed.
+	for i, advance := range []time.Duration{0, time.Second, 30 * time.Second} {
+		clk.Advance(advance)
+
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusServiceUnavailable {
+			t.Fatalf("check %d: status = %d, want 503", i, resp.StatusCode)
+		}
+
+		var body readyResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Status != "not ready" || len(body.Reachable) != 0 {
+			t.Errorf("check %d: body = %+v, want not ready with no reachable providers", i, body)
+		}
+	}
+
+	for _, p := range providers {
+		if got := p.calls.Load(); got != 2 {
+			t.Errorf("%s probed %d times, want 2", p.name, got)
+		}
+	}
+}
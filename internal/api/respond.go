@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// XML response settings.
+const (
+	xmlRootElement = "response"
+	xmlItemElement = "item"
+)
+
+// respond writes v in the format negotiated from the Accept header:
+// JSON by default, XML for clients asking for application/xml or text/xml.
+// The status must already be set on c.
+func respond(c *fiber.Ctx, v any) error {
+	if !wantsXML(c) {
+		return c.JSON(v)
+	}
+	return sendXML(c, v, xmlRootElement, fiber.MIMEApplicationXMLCharsetUTF8)
+}
+
+// wantsXML reports whether the client prefers XML over JSON.
+func wantsXML(c *fiber.Ctx) bool {
+	switch c.Accepts(fiber.MIMEApplicationJSON, fiber.MIMEApplicationXML, fiber.MIMETextXML) {
+	case fiber.MIMEApplicationXML, fiber.MIMETextXML:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendXML writes v as an XML document with the given root element.
+//
+// The document mirrors the JSON representation: v is marshaled to JSON
+// first, so field names, omitempty rules and field projection are the same
+// in both formats and models need no separate xml tags. Objects become
+// elements named after their keys, array elements become <item> elements,
+// and keys that are not valid element names become <item key="...">.
+func sendXML(c *fiber.Ctx, v any, root, contentType string) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: root}}, doc); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(buf.Bytes())
+}
+
+// encodeXMLValue encodes a decoded JSON value as the element start.
+func encodeXMLValue(enc *xml.Encoder, start xml.StartElement, v any) error {
+	switch v := v.(type) {
+	case nil:
+		return nil
+
+	case map[string]any:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			if err := encodeXMLValue(enc, xmlElement(k), v[k]); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+
+	case []any:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, it := range v {
+			if err := encodeXMLValue(enc, xml.StartElement{Name: xml.Name{Local: xmlItemElement}}, it); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+
+	default:
+		return enc.EncodeElement(fmt.Sprint(v), start)
+	}
+}
+
+// xmlElement returns the start element for an object key. Keys that are
+// not valid XML names (e.g. numeric ones) are kept in a key attribute.
+func xmlElement(key string) xml.StartElement {
+	if isXMLName(key) {
+		return xml.StartElement{Name: xml.Name{Local: key}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: xmlItemElement},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+	}
+}
+
+// isXMLName reports whether s is a simple ASCII XML element name.
+func isXMLName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if i == 0 && !letter {
+			return false
+		}
+		if !letter && (r < '0' || r > '9') && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
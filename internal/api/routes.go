@@ -1 +1,62 @@
 package api
+
+import "github.com/gofiber/fiber/v2"
+
+// RegisterRoutes mounts all API routes under /api/v1.
+func RegisterRoutes(app *fiber.App, h *Handler) {
+	api := app.Group("/api")
+	v1 := api.Group("/v1")
+
+	// Health check
+	v1.Get("/health", h.Health)
+
+	weatherGroup := v1.Group("/weather", AccessLog(h.log), h.recordLatency)
+
+	// GET /api/v1/weather/current?city=London
+	weatherGroup.Get("/current", h.CurrentWeather)
+
+	// GET /api/v1/weather/forecast?city=London&days=1
+	weatherGroup.Get("/forecast", h.Forecast)
+
+	// GET /api/v1/weather/trend?city=London
+	weatherGroup.Get("/trend", h.Trend)
+
+	// POST /api/v1/weather/prefetch?city=London&days=3
+	weatherGroup.Post("/prefetch", h.Prefetch)
+
+	// GET /api/v1/providers
+	v1.Get("/providers", h.Providers)
+
+	admin := v1.Group("/admin", h.requireAdmin)
+
+	// POST /api/v1/admin/providers/reload
+	admin.Post("/providers/reload", h.ReloadProviders)
+
+	// POST /api/v1/admin/retention/sweep?older_than=168h
+	admin.Post("/retention/sweep", h.SweepRetention)
+
+	// DELETE /api/v1/admin/scheduler/quarantine?city=London
+	admin.Delete("/scheduler/quarantine", h.ReleaseQuarantine)
+
+	// DELETE /api/v1/admin/cache?city=London
+	admin.Delete("/cache", h.EvictCache)
+
+	// GET /api/v1/admin/export, POST /api/v1/admin/import
+	admin.Get("/export", h.ExportStore)
+	admin.Post("/import", h.ImportStore)
+
+	// GET /readyz
+	app.Get("/readyz", h.Readyz)
+
+	// GET /metrics (Prometheus text format)
+	app.Get("/metrics", h.Metrics)
+
+	// GET /api/v1/scheduler/status
+	v1.Get("/scheduler/status", h.SchedulerStatus)
+
+	// GET /api/v1/cities
+	v1.Get("/cities", h.Cities)
+
+	// GET /api/v1/cache?city=London
+	v1.Get("/cache", h.CacheInfo)
+}
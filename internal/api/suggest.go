@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Bounds of the city suggestion search, so a not-found response stays
+// cheap however many cities are known.
+const (
+	maxSuggestQueryLen   = 64   // longer names are not matched
+	maxSuggestCandidates = 1000 // known cities considered
+	maxSuggestDistance   = 3
+)
+
+// mapCityError is mapServiceError for requests about city. A city no
+// provider knows yields a 404 suggesting the closest known city, if any,
+// or with CITY_AUTOCORRECT a 307 redirect to the same request for it.
+func (h *Handler) mapCityError(c *fiber.Ctx, city string, err error) error {
+	cfg := h.cfg.Current()
+	if !errors.Is(err, weather.ErrCityNotFound) || !cfg.CitySuggestions {
+		return h.mapServiceError(c, err)
+	}
+
+	suggestion, ok := h.suggestCity(city)
+	if !ok {
+		return h.mapServiceError(c, err)
+	}
+
+	if cfg.CityAutocorrect {
+		h.log.Info("redirecting unknown city to the closest known one",
+			"city", city,
+			"suggestion", suggestion,
+		)
+		args := c.Context().QueryArgs()
+		args.Set("city", suggestion)
+		return c.Redirect(c.Path()+"?"+string(args.QueryString()), fiber.StatusTemporaryRedirect)
+	}
+
+	status, code, msg := serviceErrorStatus(err)
+	return h.writeErrorWith(c, status, code, msg, suggestion)
+}
+
+// suggestCity returns the known city closest to city, case-insensitively,
+// if one is within a small edit distance. Known cities are the scheduled
+// ones, with their configured spelling, and the ones in the store.
+func (h *Handler) suggestCity(city string) (string, bool) {
+	query := weather.NormalizeCity(city)
+	if query == "" || utf8.RuneCountInString(query) > maxSuggestQueryLen {
+		return "", false
+	}
+	limit := min(maxSuggestDistance, 1+utf8.RuneCountInString(query)/5)
+
+	var candidates []string
+	for _, s := range h.schedulers {
+		candidates = append(candidates, s.Cities()...)
+	}
+	candidates = append(candidates, h.store.Cities()...)
+	if len(candidates) > maxSuggestCandidates {
+		candidates = candidates[:maxSuggestCandidates]
+	}
+
+	best, bestDist := "", limit+1
+	for _, cand := range candidates {
+		d := levenshtein(query, weather.NormalizeCity(cand), limit)
+		// 0 is the city itself, which the providers just did not find.
+		if d > 0 && d < bestDist {
+			best, bestDist = cand, d
+		}
+	}
+	return best, best != ""
+}
+
+// levenshtein returns the edit distance between a and b, or limit+1 as
+// soon as it is known to exceed limit.
+func levenshtein(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return min(prev[len(rb)], limit+1)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -3,9 +3,11 @@ package config
 import (
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/andrqxa/weather-aggregator/internal/weather"
 	"github.com/joho/godotenv"
 )
 
@@ -13,10 +15,69 @@ import (
 type Config struct {
 	Port                 string
 	FetchInterval        time.Duration
+	MinFetchInterval     time.Duration
+	QuarantineAfter      int
+	FreshnessSLAFactor   float64
 	OpenWeatherMapAPIKey string
 	WeatherAPIKey        string
+	EnableOpenMeteo      bool
+	EnableNWS            bool
+	OpenMeteoBudget      int
+	NWSBudget            int
+	NWSUserAgent         string
+	ReadinessProbeCity   string
+	ReadinessWindow      time.Duration
 	RequestTimeout       time.Duration
+	FanoutTimeout        time.Duration
+	FanoutStagger        time.Duration
+	CoalesceWindow       time.Duration
+	LatencyBudget        time.Duration
 	DefaultCities        []string
+	WarmupCities         []string
+	WarmupInterval       time.Duration
+	CacheTTL             time.Duration
+	MinRefreshInterval   time.Duration
+	ServeStaleOnFailure  bool
+	StaleMaxAge          time.Duration
+	RetentionPeriod      time.Duration
+	RetentionInterval    time.Duration
+	StoreSelfTest        bool
+	MinProviders         int
+	SourcePriority       []string
+	AggregationStrategy  string
+	ProviderWeights      map[string]float64
+	DefaultTimezone      *time.Location
+	MinForecastDays      int
+	MaxForecastDays      int
+	DefaultForecastDays  int
+	DaysValidation       string
+	BatchConcurrency     int
+	BatchMultiStatus     bool
+	MaxHistoryLimit      int
+	MaxDroppedRatio      float64
+	StrictParsing        bool
+	ErrorFormat          string
+	CitySuggestions      bool
+	CityAutocorrect      bool
+	RetryAfter           time.Duration
+	PartialContentStatus bool
+	AdminAPIKey          string
+	RequestIDHeader      string
+	MaxRequestBodyBytes  int
+	MaxResponseBytes     int
+	ProviderLang         string
+
+	GeocodingTimeout     time.Duration
+	GeocodingConcurrency int
+	GeocodingCacheTTL    time.Duration
+	GeocodingNegativeTTL time.Duration
+
+	PrefetchWorkers   int
+	PrefetchQueueSize int
+
+	PreferFresherObservation bool
+	FeelsLike                bool
+	SyntheticForecast        bool
 }
 
 // Load loads configuration from environment variables or .env file.
@@ -24,14 +85,106 @@ func Load() *Config {
 	// Load .env file if present, ignore error silently
 	_ = godotenv.Load()
 
-	return &Config{
+	return fromEnv()
+}
+
+// Reload re-reads configuration, letting values from the .env file
+// override the ones loaded previously.
+func Reload() *Config {
+	// Overload so that edits to .env replace already-set variables
+	_ = godotenv.Overload()
+
+	return fromEnv()
+}
+
+func fromEnv() *Config {
+	cfg := &Config{
 		Port:                 getEnv("FIBER_PORT", "3000"),
 		FetchInterval:        getDuration("FETCH_INTERVAL", 15*time.Minute),
+		MinFetchInterval:     getDuration("MIN_FETCH_INTERVAL", time.Minute),
+		QuarantineAfter:      getInt("SCHEDULER_QUARANTINE_AFTER", 0),
+		FreshnessSLAFactor:   getFloat("FRESHNESS_SLA_FACTOR", 2),
 		OpenWeatherMapAPIKey: getEnv("OPENWEATHERMAP_API_KEY", ""),
 		WeatherAPIKey:        getEnv("WEATHERAPI_API_KEY", ""),
+		EnableOpenMeteo:      getBool("ENABLE_OPENMETEO", true),
+		EnableNWS:            getBool("ENABLE_NWS", false),
+		OpenMeteoBudget:      getInt("OPENMETEO_DAILY_BUDGET", 0),
+		NWSBudget:            getInt("NWS_DAILY_BUDGET", 0),
+		NWSUserAgent:         getEnv("NWS_USER_AGENT", "weather-aggregator (github.com/andrqxa/weather-aggregator)"),
+		ReadinessProbeCity:   getEnv("READINESS_PROBE_CITY", "London"),
+		ReadinessWindow:      getDuration("READINESS_PROBE_WINDOW", 30*time.Second),
 		RequestTimeout:       getDuration("REQUEST_TIMEOUT", 5*time.Second),
-		DefaultCities:        parseCities(getEnv("DEFAULT_CITIES", "London")),
+		FanoutTimeout:        getDuration("FANOUT_TIMEOUT", 0),
+		FanoutStagger:        getDuration("FANOUT_STAGGER", 0),
+		CoalesceWindow:       getDuration("FETCH_COALESCE_WINDOW", 0),
+		LatencyBudget:        getDuration("PROVIDER_LATENCY_BUDGET", 0),
+		DefaultCities:        ParseCities(getEnv("DEFAULT_CITIES", "London")),
+		WarmupCities:         ParseCities(getEnv("WARMUP_CITIES", "")),
+		WarmupInterval:       getDuration("WARMUP_INTERVAL", time.Hour),
+		CacheTTL:             getDuration("CACHE_TTL", 15*time.Minute),
+		MinRefreshInterval:   getDuration("MIN_REFRESH_INTERVAL", time.Minute),
+		ServeStaleOnFailure:  getBool("SERVE_STALE_ON_FAILURE", true),
+		StaleMaxAge:          getDuration("STALE_MAX_AGE", 0),
+		RetentionPeriod:      getDuration("RETENTION_PERIOD", 0),
+		RetentionInterval:    getDuration("RETENTION_SWEEP_INTERVAL", time.Hour),
+		StoreSelfTest:        getBool("STORE_SELFTEST", false),
+		MinProviders:         getInt("MIN_PROVIDERS_FOR_AGGREGATE", 1),
+		SourcePriority:       ParseCities(strings.ToLower(getEnv("SOURCE_PRIORITY", ""))),
+		AggregationStrategy:  strings.ToLower(getEnv("AGGREGATION_STRATEGY", "first")),
+		ProviderWeights:      getWeights("PROVIDER_WEIGHTS"),
+		DefaultTimezone:      getLocation("DEFAULT_TIMEZONE", time.UTC),
+		MinForecastDays:      getInt("MIN_FORECAST_DAYS", 1),
+		MaxForecastDays:      getInt("MAX_FORECAST_DAYS", 7),
+		DefaultForecastDays:  getInt("DEFAULT_FORECAST_DAYS", 3),
+		DaysValidation:       getEnv("FORECAST_DAYS_VALIDATION", "strict"),
+		BatchConcurrency:     getInt("BATCH_CONCURRENCY", 4),
+		BatchMultiStatus:     getBool("BATCH_MULTISTATUS", false),
+		MaxHistoryLimit:      getInt("MAX_HISTORY_LIMIT", 50),
+		MaxDroppedRatio:      getFloat("FORECAST_MAX_DROPPED_RATIO", 0.5),
+		StrictParsing:        getBool("STRICT_PROVIDER_PARSING", false),
+		ErrorFormat:          getEnv("ERROR_FORMAT", "simple"),
+		CitySuggestions:      getBool("CITY_SUGGESTIONS", true),
+		CityAutocorrect:      getBool("CITY_AUTOCORRECT", false),
+		RetryAfter:           getDuration("RETRY_AFTER", 30*time.Second),
+		PartialContentStatus: getBool("PARTIAL_CONTENT_STATUS", false),
+		AdminAPIKey:          getEnv("ADMIN_API_KEY", ""),
+		RequestIDHeader:      getEnv("REQUEST_ID_HEADER", "X-Request-ID"),
+		MaxResponseBytes:     getInt("MAX_RESPONSE_BYTES", 4<<20),
+		MaxRequestBodyBytes:  getInt("MAX_REQUEST_BODY_BYTES", 64<<20),
+		ProviderLang:         getEnv("PROVIDER_LANG", ""),
+
+		GeocodingTimeout:     getDuration("GEOCODING_TIMEOUT", 3*time.Second),
+		GeocodingConcurrency: getInt("GEOCODING_CONCURRENCY", 4),
+		GeocodingCacheTTL:    getDuration("GEOCODING_CACHE_TTL", 24*time.Hour),
+		GeocodingNegativeTTL: getDuration("GEOCODING_NEGATIVE_TTL", time.Hour),
+
+		PrefetchWorkers:   getInt("PREFETCH_WORKERS", 2),
+		PrefetchQueueSize: getInt("PREFETCH_QUEUE_SIZE", 100),
+
+		PreferFresherObservation: getBool("PREFER_FRESHER_OBSERVATION", true),
+		FeelsLike:                getBool("INCLUDE_FEELS_LIKE", true),
+		SyntheticForecast:        getBool("SYNTHETIC_FORECAST_FALLBACK", false),
+	}
+
+	// Polling every city against every provider too often gets the
+	// instance rate-limited or banned, so scheduler intervals are floored.
+	cfg.FetchInterval = atLeast("FETCH_INTERVAL", cfg.FetchInterval, cfg.MinFetchInterval)
+	cfg.WarmupInterval = atLeast("WARMUP_INTERVAL", cfg.WarmupInterval, cfg.MinFetchInterval)
+
+	return cfg
+}
+
+// atLeast returns d, raised to floor with a warning when it is smaller.
+func atLeast(key string, d, floor time.Duration) time.Duration {
+	if d >= floor {
+		return d
 	}
+	slog.Warn("interval below minimum, using minimum",
+		"key", key,
+		"value", d.String(),
+		"minimum", floor.String(),
+	)
+	return floor
 }
 
 func getDuration(key string, defaultValue time.Duration) time.Duration {
@@ -49,6 +202,86 @@ func getDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+func getInt(key string, defaultValue int) int {
+	if v, ok := os.LookupEnv(key); ok {
+		n, err := strconv.Atoi(v)
+		if err == nil {
+			return n
+		}
+		slog.Warn("invalid integer",
+			"key", key,
+			"value", v,
+			"default", defaultValue,
+		)
+	}
+	return defaultValue
+}
+
+func getFloat(key string, defaultValue float64) float64 {
+	if v, ok := os.LookupEnv(key); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err == nil {
+			return f
+		}
+		slog.Warn("invalid float",
+			"key", key,
+			"value", v,
+			"default", defaultValue,
+		)
+	}
+	return defaultValue
+}
+
+func getBool(key string, defaultValue bool) bool {
+	if v, ok := os.LookupEnv(key); ok {
+		b, err := strconv.ParseBool(v)
+		if err == nil {
+			return b
+		}
+		slog.Warn("invalid boolean",
+			"key", key,
+			"value", v,
+			"default", defaultValue,
+		)
+	}
+	return defaultValue
+}
+
+func getLocation(key string, defaultValue *time.Location) *time.Location {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		loc, err := time.LoadLocation(v)
+		if err == nil {
+			return loc
+		}
+		slog.Warn("invalid timezone",
+			"key", key,
+			"value", v,
+			"default", defaultValue.String(),
+		)
+	}
+	return defaultValue
+}
+
+// getWeights parses a "name=weight,name=weight" list. Malformed or negative
+// entries are skipped with a warning.
+func getWeights(key string) map[string]float64 {
+	res := make(map[string]float64)
+	for _, entry := range ParseCities(getEnv(key, "")) {
+		name, raw, found := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		w, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if !found || name == "" || err != nil || w < 0 {
+			slog.Warn("invalid weight entry",
+				"key", key,
+				"entry", entry,
+			)
+			continue
+		}
+		res[name] = w
+	}
+	return res
+}
+
 func getEnv(key string, defaultValue string) string {
 	if v, ok := os.LookupEnv(key); ok {
 		return v
@@ -56,16 +289,22 @@ func getEnv(key string, defaultValue string) string {
 	return defaultValue
 }
 
-func parseCities(raw string) []string {
+// ParseCities splits a comma-separated list, trimming spaces and dropping
+// empty entries. Entries with the same weather.NormalizeCity key, e.g.
+// differing only in case or inner spacing, are duplicates, as city
+// lookups are; the first spelling is kept.
+func ParseCities(raw string) []string {
 	parts := strings.Split(raw, ",")
 	res := make([]string, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
 
 	for _, p := range parts {
 		p = strings.TrimSpace(p)
-		if p != "" {
+		key := weather.NormalizeCity(p)
+		if p != "" && !seen[key] {
+			seen[key] = true
 			res = append(res, p)
 		}
-
 	}
 	return res
 }
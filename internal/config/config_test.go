@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestParseCities(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{"", []string{}},
+		{" London , Paris,,", []string{"London", "Paris"}},
+		{"London,london,LONDON", []string{"London"}},
+		{"New York, new  york,NEW\tYORK", []string{"New York"}},
+		{"München,MÜNCHEN,münchen", []string{"München"}},
+		{"Ωμέγας,ΩΜΈΓΑΣ", []string{"Ωμέγας"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseCities(tt.raw); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseCities(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestOpenMeteoBudgetDefaultsToUnknown(t *testing.T) {
+	t.Setenv("OPENMETEO_DAILY_BUDGET", "")
+	os.Unsetenv("OPENMETEO_DAILY_BUDGET")
+
+	if got := fromEnv().OpenMeteoBudget; got != 0 {
+		t.Errorf("OpenMeteoBudget = %d, want 0", got)
+	}
+}
@@ -0,0 +1,27 @@
+package config
+
+import "sync/atomic"
+
+// Holder publishes the active Config. Readers get an immutable snapshot
+// via Current; reloads replace it atomically with Store.
+// Snapshots must not be modified after they are stored.
+type Holder struct {
+	p atomic.Pointer[Config]
+}
+
+// NewHolder creates a Holder publishing cfg.
+func NewHolder(cfg *Config) *Holder {
+	h := &Holder{}
+	h.p.Store(cfg)
+	return h
+}
+
+// Current returns the active configuration snapshot.
+func (h *Holder) Current() *Config {
+	return h.p.Load()
+}
+
+// Store publishes cfg as the active configuration.
+func (h *Holder) Store(cfg *Config) {
+	h.p.Store(cfg)
+}
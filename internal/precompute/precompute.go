@@ -0,0 +1,119 @@
+// Package precompute keeps daily forecast summaries for scheduled cities
+// ready ahead of requests.
+//
+// Provider results are aggregated when they are fetched; what remains on
+// the request path is deriving per-day summaries from forecast items.
+// Cache recomputes them whenever the store saves new data for a scheduled
+// city, so requests for those cities reuse the result. Other cities fall
+// back to computing summaries on demand.
+package precompute
+
+import (
+	"sync"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+// entry holds the daily summaries of one stored forecast.
+type entry struct {
+	updatedAt time.Time // of the forecast the summaries were computed from
+	daily     []weather.DailySummary
+}
+
+type entryKey struct {
+	city string
+	days int
+}
+
+// Cache holds precomputed entries, refreshed through a store subscription.
+// It is safe for concurrent use.
+type Cache struct {
+	store     storage.Store
+	scheduled func(city string) bool
+
+	mu      sync.RWMutex
+	entries map[entryKey]entry
+}
+
+// NewCache creates a Cache and subscribes it to store. Only cities for
+// which scheduled reports true are precomputed.
+func NewCache(store storage.Store, scheduled func(city string) bool) *Cache {
+	c := &Cache{
+		store:     store,
+		scheduled: scheduled,
+		entries:   make(map[entryKey]entry),
+	}
+	store.Subscribe(c.onUpdate)
+	return c
+}
+
+// onUpdate recomputes the entry affected by a forecast save. Current
+// weather needs no derivation and is served from the store as is.
+func (c *Cache) onUpdate(u storage.Update) {
+	if u.Days == 0 || !c.scheduled(u.City) {
+		return
+	}
+
+	f, ok := c.store.GetForecast(u.City, u.Days)
+	if !ok {
+		return
+	}
+	daily := weather.DailySummaries(f.Items)
+
+	c.mu.Lock()
+	c.entries[entryKey{city: weather.NormalizeCity(u.City), days: u.Days}] = entry{
+		updatedAt: f.UpdatedAt,
+		daily:     daily,
+	}
+	c.mu.Unlock()
+}
+
+// Daily returns precomputed daily summaries for f, a forecast for city as
+// stored or trimmed to fewer days (see weather.FirstDays). It reports false
+// unless an entry was computed from the same fetch (matched by UpdatedAt)
+// and has a summary for every day of f.
+func (c *Cache) Daily(city string, f weather.Forecast) ([]weather.DailySummary, bool) {
+	if f.UpdatedAt.IsZero() {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key := weather.NormalizeCity(city)
+	for k, e := range c.entries {
+		if k.city != key || !e.updatedAt.Equal(f.UpdatedAt) {
+			continue
+		}
+		if daily, ok := daysOf(e.daily, f.Items); ok {
+			return daily, true
+		}
+	}
+	return nil, false
+}
+
+// daysOf selects the summaries for the UTC days of items. Trimming may
+// drop leading days, e.g. a forecast fetched before midnight and served
+// after it, so summaries are matched by date rather than position.
+// Trimming keeps or drops whole days, so a matched summary covers the
+// same items. Both are expected in chronological order.
+func daysOf(daily []weather.DailySummary, items []weather.ForecastItem) ([]weather.DailySummary, bool) {
+	res := make([]weather.DailySummary, 0, len(daily))
+	i := 0
+	for _, it := range items {
+		date := it.TimeStamp.UTC().Format(time.DateOnly)
+		if len(res) > 0 && res[len(res)-1].Date == date {
+			continue
+		}
+		for i < len(daily) && daily[i].Date != date {
+			i++
+		}
+		if i == len(daily) {
+			return nil, false
+		}
+		res = append(res, daily[i])
+	}
+	return res, true
+}
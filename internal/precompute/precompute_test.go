@@ -0,0 +1,64 @@
+package precompute_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/precompute"
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+// threeDays returns an hourly forecast over three UTC days starting at
+// start, with the temperature rising by one degree per day.
+func threeDays(start time.Time) weather.Forecast {
+	var items []weather.ForecastItem
+	for h := range 72 {
+		items = append(items, weather.ForecastItem{
+			TimeStamp:   start.Add(time.Duration(h) * time.Hour),
+			Temperature: float64(h / 24),
+			Condition:   weather.ConditionCloudy,
+		})
+	}
+	return weather.Forecast{City: "London", Days: 3, ActualDays: 3, Items: items, UpdatedAt: start.Add(23 * time.Hour)}
+}
+
+func TestDailyAfterMidnight(t *testing.T) {
+	day1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := storage.NewInMemoryStore(clock.NewManual(day1))
+	cache := precompute.NewCache(store, func(string) bool { return true })
+
+	f := threeDays(day1)
+	store.SaveForecast("London", 3, f)
+
+	// Fetched before midnight, served for two days after it: day 1 is
+	// trimmed away.
+	trimmed := weather.FirstDays(f, 2, day1.Add(25*time.Hour))
+
+	got, ok := cache.Daily("London", trimmed)
+	if !ok {
+		t.Fatal("no precomputed summaries")
+	}
+	if want := weather.DailySummaries(trimmed.Items); !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if len(got) != 2 || got[0].Date != "2025-01-02" {
+		t.Errorf("got %+v, want summaries for 2025-01-02 and 2025-01-03", got)
+	}
+}
+
+func TestDailyOtherFetch(t *testing.T) {
+	day1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := storage.NewInMemoryStore(clock.NewManual(day1))
+	cache := precompute.NewCache(store, func(string) bool { return true })
+
+	f := threeDays(day1)
+	store.SaveForecast("London", 3, f)
+
+	f.UpdatedAt = f.UpdatedAt.Add(time.Minute)
+	if _, ok := cache.Daily("London", f); ok {
+		t.Error("summaries of another fetch were returned")
+	}
+}
@@ -0,0 +1,144 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+// ErrQueueFull is returned by Prefetcher.Enqueue when no more jobs fit.
+var ErrQueueFull = errors.New("prefetch queue is full")
+
+type prefetchJob struct {
+	city string
+	days int
+}
+
+// key identifies a job for deduplication; cities are case-insensitive.
+func (j prefetchJob) key() prefetchJob {
+	return prefetchJob{city: weather.NormalizeCity(j.city), days: j.days}
+}
+
+// Prefetcher warms the store for ad-hoc cities in the background.
+// Jobs go through a bounded queue served by a fixed number of workers,
+// and a job already queued or running for the same city and days is not
+// queued again.
+type Prefetcher struct {
+	service        *weather.Service
+	store          storage.Store
+	requestTimeout time.Duration
+	workers        int
+	log            *slog.Logger
+
+	queue chan prefetchJob
+
+	mu      sync.Mutex
+	pending map[prefetchJob]bool
+}
+
+// NewPrefetcher creates a Prefetcher with the given number of workers
+// and queue size. Workers run once Start is called.
+func NewPrefetcher(
+	service *weather.Service,
+	store storage.Store,
+	requestTimeout time.Duration,
+	workers int,
+	queueSize int,
+	log *slog.Logger,
+) *Prefetcher {
+	return &Prefetcher{
+		service:        service,
+		store:          store,
+		requestTimeout: requestTimeout,
+		workers:        max(workers, 1),
+		log:            log,
+		queue:          make(chan prefetchJob, max(queueSize, 1)),
+		pending:        make(map[prefetchJob]bool),
+	}
+}
+
+// Enqueue schedules a background fetch of current weather and a days-long
+// forecast for city. It reports false if an identical job is already
+// pending, and returns ErrQueueFull if the queue has no room.
+func (p *Prefetcher) Enqueue(city string, days int) (bool, error) {
+	job := prefetchJob{city: city, days: days}
+	key := job.key()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending[key] {
+		return false, nil
+	}
+
+	select {
+	case p.queue <- job:
+		p.pending[key] = true
+		return true, nil
+	default:
+		return false, ErrQueueFull
+	}
+}
+
+// Start runs the workers until ctx is cancelled. Queued jobs that have
+// not started by then are dropped.
+func (p *Prefetcher) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range p.workers {
+		wg.Go(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-p.queue:
+					p.run(job)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// run fetches and stores data for a single job. It uses its own timeout,
+// detached from the HTTP request that enqueued it.
+func (p *Prefetcher) run(job prefetchJob) {
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, job.key())
+		p.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.requestTimeout)
+	defer cancel()
+
+	current, err := p.service.GetCurrentWeather(ctx, job.city)
+	if err != nil {
+		p.log.Warn("prefetch failed to fetch current weather",
+			"city", job.city,
+			"error", err,
+		)
+	} else {
+		p.store.SaveCurrent(job.city, current)
+	}
+
+	forecast, err := p.service.GetForecast(ctx, job.city, job.days)
+	if err != nil {
+		p.log.Warn("prefetch failed to fetch forecast",
+			"city", job.city,
+			"days", job.days,
+			"error", err,
+		)
+	} else {
+		p.store.SaveForecast(job.city, job.days, forecast)
+	}
+
+	p.log.Info("prefetch finished",
+		"city", job.city,
+		"days", job.days,
+	)
+}
@@ -2,10 +2,15 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/andrqxa/weather-aggregator/internal/clock"
 	"github.com/andrqxa/weather-aggregator/internal/storage"
 	"github.com/andrqxa/weather-aggregator/internal/weather"
 )
@@ -14,24 +19,65 @@ import (
 // and stores results in the in-memory storage.
 type Scheduler struct {
 	service        *weather.Service
-	store          *storage.InMemoryStore
-	cities         []string
-	interval       time.Duration
+	store          storage.Store
 	requestTimeout time.Duration
 	defaultDays    int
+	clock          clock.Clock
+
+	// mu guards reloadable settings and last run stats.
+	mu       sync.RWMutex
+	cities   []string
+	interval time.Duration
+	lastRun  *RunStats
+	resetCh  chan struct{} // signals Start to recreate the ticker
+
+	// quarantineAfter is the number of consecutive runs in which no
+	// provider found a city after which it is no longer fetched; 0
+	// disables quarantine. Keys of failures and quarantined are
+	// normalized city names.
+	quarantineAfter int
+	failures        map[string]int
+	quarantined     map[string]QuarantinedCity
 
 	log     *slog.Logger
 	running int32 // 0 - idle, 1 - job in progress
 }
 
+// RunStats describes a completed scheduler run.
+type RunStats struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Succeeded int // cities whose fetches all succeeded
+	Failed    int
+}
+
+// QuarantinedCity is a city the scheduler stopped fetching after no
+// provider found it on QuarantineAfter runs in a row.
+type QuarantinedCity struct {
+	City     string
+	Since    time.Time
+	Failures int
+}
+
+// Status is a snapshot of the scheduler state.
+type Status struct {
+	Cities      []string
+	Interval    time.Duration
+	Running     bool
+	LastRun     *RunStats         // nil until the first run completes
+	Quarantined []QuarantinedCity // sorted by city
+}
+
 // NewScheduler creates a new Scheduler instance.
+// If clk is nil, the real clock is used.
 func NewScheduler(
 	service *weather.Service,
-	store *storage.InMemoryStore,
+	store storage.Store,
 	cities []string,
 	interval time.Duration,
 	requestTimeout time.Duration,
 	defaultDays int,
+	clk clock.Clock,
 	log *slog.Logger,
 ) *Scheduler {
 	return &Scheduler{
@@ -41,25 +87,179 @@ func NewScheduler(
 		interval:       interval,
 		requestTimeout: requestTimeout,
 		defaultDays:    defaultDays,
+		clock:          clock.OrReal(clk),
+		resetCh:        make(chan struct{}, 1),
+		failures:       make(map[string]int),
+		quarantined:    make(map[string]QuarantinedCity),
 		log:            log,
 	}
 }
 
+// SetCities replaces the list of cities fetched on each tick.
+// The change takes effect from the next tick. Failure counts and
+// quarantine of cities no longer in the list are dropped.
+func (s *Scheduler) SetCities(cities []string) {
+	s.mu.Lock()
+	s.cities = slices.Clone(cities)
+	keep := make(map[string]bool, len(cities))
+	for _, city := range cities {
+		keep[weather.NormalizeCity(city)] = true
+	}
+	for key := range s.failures {
+		if !keep[key] {
+			delete(s.failures, key)
+		}
+	}
+	for key := range s.quarantined {
+		if !keep[key] {
+			delete(s.quarantined, key)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(cities) == 0 {
+		s.warnNoCities()
+		return
+	}
+	s.log.Info("scheduler cities updated", "cities", cities)
+	s.CheckTiming()
+}
+
+// warnNoCities tells the operator that ticks will do nothing, which
+// otherwise goes unnoticed, e.g. after a typo in the cities list.
+func (s *Scheduler) warnNoCities() {
+	s.log.Warn("scheduler has no cities, nothing will be prefetched until cities are configured")
+}
+
+// SetInterval changes the tick interval. The running ticker is recreated
+// with the new interval.
+func (s *Scheduler) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		s.log.Warn("ignoring non-positive scheduler interval", "interval", interval.String())
+		return
+	}
+
+	s.mu.Lock()
+	s.interval = interval
+	s.mu.Unlock()
+
+	// Non-blocking: a pending reset already picks up the latest interval.
+	select {
+	case s.resetCh <- struct{}{}:
+	default:
+	}
+
+	s.log.Info("scheduler interval updated", "interval", interval.String())
+	s.CheckTiming()
+}
+
+// CheckTiming reports whether a run fits in the tick interval even if
+// every fetch runs into the request timeout. Cities are fetched one at a
+// time, each within one request timeout, so the worst case is
+// cities × timeout. If that exceeds the interval, a warning with the
+// numbers is logged: ticks would be skipped while the previous run is
+// still in progress.
+func (s *Scheduler) CheckTiming() bool {
+	cities, interval := len(s.Cities()), s.Interval()
+	worst := time.Duration(cities) * s.requestTimeout
+	if worst <= interval {
+		return true
+	}
+
+	s.log.Warn("scheduler run can outlast its interval, ticks will be skipped while it is in progress",
+		"cities", cities,
+		"request_timeout", s.requestTimeout.String(),
+		"worst_case_run", worst.String(),
+		"interval", interval.String(),
+		"hint", "use an interval of at least cities × REQUEST_TIMEOUT, a shorter REQUEST_TIMEOUT, or fewer cities",
+	)
+	return false
+}
+
+// SetQuarantineAfter sets the number of consecutive not-found runs after
+// which a city is quarantined. 0 disables quarantine; already
+// quarantined cities stay so until released.
+func (s *Scheduler) SetQuarantineAfter(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quarantineAfter = max(n, 0)
+}
+
+// Release takes city out of quarantine, so it is fetched again from the
+// next tick. It reports whether city was quarantined.
+func (s *Scheduler) Release(city string) bool {
+	key := weather.NormalizeCity(city)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.quarantined[key]; !ok {
+		return false
+	}
+	delete(s.quarantined, key)
+	delete(s.failures, key)
+	s.log.Info("city released from quarantine", "city", city)
+	return true
+}
+
+// Cities returns a copy of the currently scheduled cities.
+func (s *Scheduler) Cities() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.cities)
+}
+
+// Interval returns the current tick interval.
+func (s *Scheduler) Interval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.interval
+}
+
+// Status returns the current scheduler state and the last run stats.
+func (s *Scheduler) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	st := Status{
+		Cities:   slices.Clone(s.cities),
+		Interval: s.interval,
+		Running:  atomic.LoadInt32(&s.running) == 1,
+	}
+	if s.lastRun != nil {
+		run := *s.lastRun
+		st.LastRun = &run
+	}
+	for _, q := range s.quarantined {
+		st.Quarantined = append(st.Quarantined, q)
+	}
+	slices.SortFunc(st.Quarantined, func(a, b QuarantinedCity) int {
+		return strings.Compare(a.City, b.City)
+	})
+	return st
+}
+
 // Start runs periodic jobs until the context is cancelled.
 func (s *Scheduler) Start(ctx context.Context) {
 	s.log.Info("scheduler started",
-		"interval", s.interval.String(),
-		"cities", s.cities,
+		"interval", s.Interval().String(),
+		"cities", s.Cities(),
 	)
+	if len(s.Cities()) == 0 {
+		s.warnNoCities()
+	}
 
-	ticker := time.NewTicker(s.interval)
-	defer ticker.Stop()
+	ticker := time.NewTicker(s.Interval())
+	defer func() { ticker.Stop() }()
 
 	for {
 		select {
 		case <-ctx.Done():
 			s.log.Info("scheduler stopping due to context cancellation")
 			return
+		case <-s.resetCh:
+			ticker.Stop()
+			ticker = time.NewTicker(s.Interval())
 		case <-ticker.C:
 			s.runOnce()
 		}
@@ -69,6 +269,12 @@ func (s *Scheduler) Start(ctx context.Context) {
 // runOnce executes a single scheduler tick.
 // It ensures that jobs do not overlap using an atomic flag.
 func (s *Scheduler) runOnce() {
+	cities := s.Cities()
+	if len(cities) == 0 {
+		// Already warned when the cities were set; keep ticks quiet.
+		return
+	}
+
 	// Prevent overlapping runs.
 	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
 		s.log.Warn("previous scheduler run still in progress, skipping this tick")
@@ -76,23 +282,89 @@ func (s *Scheduler) runOnce() {
 	}
 	defer atomic.StoreInt32(&s.running, 0)
 
-	start := time.Now()
+	start := s.clock.Now()
 	s.log.Info("scheduler tick started")
 
-	for _, city := range s.cities {
-		s.runForCity(city)
+	stats := RunStats{StartedAt: start.UTC()}
+
+	for _, city := range cities {
+		if s.isQuarantined(city) {
+			continue
+		}
+		outcome := s.runForCity(city)
+		if outcome == cityOK {
+			stats.Succeeded++
+		} else {
+			stats.Failed++
+		}
+		s.recordOutcome(city, outcome)
 	}
 
-	duration := time.Since(start)
+	stats.Duration = s.clock.Now().Sub(start)
+
+	s.mu.Lock()
+	s.lastRun = &stats
+	s.mu.Unlock()
+
 	s.log.Info("scheduler tick finished",
-		"duration", duration.String(),
-		"cities", len(s.cities),
+		"duration", stats.Duration.String(),
+		"cities", len(cities),
+		"succeeded", stats.Succeeded,
+		"failed", stats.Failed,
+	)
+}
+
+func (s *Scheduler) isQuarantined(city string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.quarantined[weather.NormalizeCity(city)]
+	return ok
+}
+
+// cityOutcome is the result of a scheduler run for one city.
+type cityOutcome int
+
+const (
+	cityOK       cityOutcome = iota // both fetches succeeded
+	cityFailed                      // a fetch failed, e.g. providers were down
+	cityNotFound                    // no provider knows the city
+)
+
+// recordOutcome tracks consecutive runs in which city was not found and
+// quarantines it once they reach the threshold, e.g. a misspelled name
+// no provider resolves, which would otherwise burn fetches on every tick.
+// Any other outcome resets the count: an outage says nothing about the
+// name.
+func (s *Scheduler) recordOutcome(city string, outcome cityOutcome) {
+	key := weather.NormalizeCity(city)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if outcome != cityNotFound {
+		delete(s.failures, key)
+		return
+	}
+	s.failures[key]++
+	if s.quarantineAfter <= 0 || s.failures[key] < s.quarantineAfter {
+		return
+	}
+
+	s.quarantined[key] = QuarantinedCity{
+		City:     city,
+		Since:    s.clock.Now().UTC(),
+		Failures: s.failures[key],
+	}
+	s.log.Error("giving up on city, quarantining it until released or removed from the cities list",
+		"city", city,
+		"consecutive_failures", s.failures[key],
 	)
 }
 
 // runForCity fetches current weather and forecast for a single city
-// and stores results in the in-memory storage.
-func (s *Scheduler) runForCity(city string) {
+// and stores results in the in-memory storage. The city counts as not
+// found only if both fetches failed with weather.ErrCityNotFound.
+func (s *Scheduler) runForCity(city string) cityOutcome {
 	ctx, cancel := context.WithTimeout(context.Background(), s.requestTimeout)
 	defer cancel()
 
@@ -102,25 +374,34 @@ func (s *Scheduler) runForCity(city string) {
 	)
 
 	// Fetch current weather.
-	current, err := s.service.GetCurrentWeather(ctx, city)
-	if err != nil {
+	current, currentErr := s.service.GetCurrentWeather(ctx, city)
+	if currentErr != nil {
 		s.log.Warn("scheduler failed to fetch current weather",
 			"city", city,
-			"error", err,
+			"error", currentErr,
 		)
 	} else {
-		s.store.SaveCurrent(city, current, time.Now().UTC())
+		s.store.SaveCurrent(city, current)
 	}
 
 	// Fetch forecast.
-	forecast, err := s.service.GetForecast(ctx, city, s.defaultDays)
-	if err != nil {
+	forecast, forecastErr := s.service.GetForecast(ctx, city, s.defaultDays)
+	if forecastErr != nil {
 		s.log.Warn("scheduler failed to fetch forecast",
 			"city", city,
 			"days", s.defaultDays,
-			"error", err,
+			"error", forecastErr,
 		)
 	} else {
-		s.store.SaveForecast(city, s.defaultDays, forecast, time.Now().UTC())
+		s.store.SaveForecast(city, s.defaultDays, forecast)
+	}
+
+	switch {
+	case currentErr == nil && forecastErr == nil:
+		return cityOK
+	case errors.Is(currentErr, weather.ErrCityNotFound) && errors.Is(forecastErr, weather.ErrCityNotFound):
+		return cityNotFound
+	default:
+		return cityFailed
 	}
 }
@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/storage"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+var discardLog = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// stubProvider answers after delay with err, or a fixed reading if err
+// is nil, counting the calls it receives.
+type stubProvider struct {
+	delay time.Duration
+	err   error
+
+	currentCalls  atomic.Int32
+	forecastCalls atomic.Int32
+}
+
+func (p *stubProvider) Name() string { return string(weather.SourceOpenMeteo) }
+
+func (p *stubProvider) FetchCurrent(ctx context.Context, city string) (weather.CurrentWeather, error) {
+	p.currentCalls.Add(1)
+	if err := p.wait(ctx); err != nil {
+		return weather.CurrentWeather{}, err
+	}
+	return weather.CurrentWeather{
+		City:        city,
+		Temperature: 10,
+		Humidity:    80,
+		WindSpeed:   4,
+		Condition:   weather.ConditionRain,
+		Source:      weather.SourceOpenMeteo,
+		ObservedAt:  testNow,
+	}, nil
+}
+
+func (p *stubProvider) FetchForecast(ctx context.Context, city string, days int) (weather.Forecast, error) {
+	p.forecastCalls.Add(1)
+	if err := p.wait(ctx); err != nil {
+		return weather.Forecast{}, err
+	}
+	return weather.Forecast{
+		City: city,
+		Days: days,
+		Items: []weather.ForecastItem{{
+			TimeStamp:   testNow,
+			Temperature: 10,
+			Humidity:    80,
+			WindSpeed:   4,
+			Condition:   weather.ConditionRain,
+			Source:      weather.SourceOpenMeteo,
+		}},
+		Source: weather.SourceOpenMeteo,
+	}, nil
+}
+
+func (p *stubProvider) wait(ctx context.Context) error {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return p.err
+}
+
+func newTestScheduler(p weather.Provider, cities []string, interval time.Duration, opts ...weather.Option) (*Scheduler, *weather.Service) {
+	clk := clock.NewManual(testNow)
+	svc := weather.NewService([]weather.Provider{p}, clk, opts...)
+	s := NewScheduler(svc, storage.NewInMemoryStore(clk), cities, interval, time.Second, 1, clk, discardLog)
+	return s, svc
+}
+
+func TestTickAndRequestShareOneFetch(t *testing.T) {
+	p := &stubProvider{delay: 100 * time.Millisecond}
+	s, svc := newTestScheduler(p, []string{"London"}, time.Minute)
+
+	var wg sync.WaitGroup
+	wg.Go(s.runOnce)
+	wg.Go(func() {
+		// Arrive while the tick's current weather fetch is in flight.
+		time.Sleep(20 * time.Millisecond)
+		if _, err := svc.GetCurrentWeather(context.Background(), "london"); err != nil {
+			t.Errorf("GetCurrentWeather: %v", err)
+		}
+	})
+	wg.Wait()
+
+	if got := p.currentCalls.Load(); got != 1 {
+		t.Errorf("provider got %d current weather calls, want 1", got)
+	}
+}
+
+func TestQuarantineCountsOnlyNotFound(t *testing.T) {
+	p := &stubProvider{err: weather.ErrCityNotFound}
+	s, _ := newTestScheduler(p, []string{"Lodnon"}, time.Minute)
+	s.SetQuarantineAfter(2)
+
+	s.runOnce()
+
+	// An outage in between says nothing about the name and resets the count.
+	p.err = weather.ErrProviderUnavailable
+	s.runOnce()
+	s.runOnce()
+	if s.isQuarantined("Lodnon") {
+		t.Fatal("city quarantined after provider outages")
+	}
+
+	p.err = weather.ErrCityNotFound
+	s.runOnce()
+	if s.isQuarantined("Lodnon") {
+		t.Fatal("city quarantined after one not-found run since the outage")
+	}
+	s.runOnce()
+	if !s.isQuarantined("Lodnon") {
+		t.Fatal("city not quarantined after two not-found runs in a row")
+	}
+
+	calls := p.currentCalls.Load()
+	s.runOnce()
+	if got := p.currentCalls.Load(); got != calls {
+		t.Errorf("quarantined city was fetched again")
+	}
+	if q := s.Status().Quarantined; len(q) != 1 || q[0].Failures != 2 {
+		t.Errorf("quarantined = %+v, want Lodnon after 2 runs", q)
+	}
+}
+
+func TestCheckTiming(t *testing.T) {
+	cities := []string{"London", "Paris", "Berlin"}
+
+	tests := []struct {
+		interval time.Duration
+		want     bool
+	}{
+		{3 * time.Second, true}, // worst case of 3 × 1s fits exactly
+		{time.Minute, true},
+		{2 * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		s, _ := newTestScheduler(&stubProvider{}, cities, tt.interval)
+		var logs strings.Builder
+		s.log = slog.New(slog.NewTextHandler(&logs, nil))
+
+		if got := s.CheckTiming(); got != tt.want {
+			t.Errorf("interval %v: CheckTiming() = %v, want %v", tt.interval, got, tt.want)
+		}
+		if warned := strings.Contains(logs.String(), "worst_case_run=3s"); warned == tt.want {
+			t.Errorf("interval %v: warning logged = %v, want %v", tt.interval, warned, !tt.want)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package storage
 
 import (
+	"context"
+	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/andrqxa/weather-aggregator/internal/clock"
 	"github.com/andrqxa/weather-aggregator/internal/weather"
 )
 
@@ -16,20 +20,21 @@ type forecastKey struct {
 }
 
 type CurrentSnapshot struct {
-	At   time.Time
-	Data weather.CurrentWeather
+	At   time.Time              `json:"at"`
+	Data weather.CurrentWeather `json:"data"`
 }
 
 type ForecastSnapshot struct {
-	At   time.Time
-	Days int
-	Data weather.Forecast
+	At   time.Time        `json:"at"`
+	Days int              `json:"days"`
+	Data weather.Forecast `json:"data"`
 }
 
 // InMemoryStore keeps latest and historical weather data in memory.
 // It is safe for concurrent use.
 type InMemoryStore struct {
-	mu sync.RWMutex
+	mu    sync.RWMutex
+	clock clock.Clock
 
 	current   map[string]weather.CurrentWeather
 	forecast  map[forecastKey]weather.Forecast
@@ -37,39 +42,100 @@ type InMemoryStore struct {
 
 	currentHistory  map[string][]CurrentSnapshot
 	forecastHistory map[forecastKey][]ForecastSnapshot
+
+	// names maps normalized city keys to the name the city was first
+	// saved under, which is what listings show.
+	names map[string]string
+
+	preferFresher bool // see SetPreferFresher
+
+	subsMu sync.RWMutex
+	subs   []func(Update)
 }
 
 // NewInMemoryStore creates a new empty in-memory store instance.
-func NewInMemoryStore() *InMemoryStore {
+// The clock is used to timestamp saved entries; if nil, the real clock is used.
+func NewInMemoryStore(clk clock.Clock) *InMemoryStore {
 	return &InMemoryStore{
+		clock:           clock.OrReal(clk),
 		current:         make(map[string]weather.CurrentWeather),
 		forecast:        make(map[forecastKey]weather.Forecast),
 		lastFetch:       make(map[string]time.Time),
 		currentHistory:  make(map[string][]CurrentSnapshot),
 		forecastHistory: make(map[forecastKey][]ForecastSnapshot),
+		names:           make(map[string]string),
 	}
 }
 
-// SaveCurrent stores latest current weather for a city, updates last fetch time
-// and appends entry to the history with a bounded size.
-func (s *InMemoryStore) SaveCurrent(city string, w weather.CurrentWeather, fetchedAt time.Time) {
+// nameLocked records city as the display name of key unless it already
+// has one. The name is cloned: callers may pass strings backed by a
+// reused request buffer. s.mu must be held.
+func (s *InMemoryStore) nameLocked(key, city string) {
+	if _, ok := s.names[key]; !ok {
+		s.names[key] = strings.Clone(strings.Join(strings.Fields(city), " "))
+	}
+}
+
+// displayNameLocked returns the display name of key, or key itself if
+// none was recorded. s.mu must be held.
+func (s *InMemoryStore) displayNameLocked(key string) string {
+	if name, ok := s.names[key]; ok {
+		return name
+	}
+	return key
+}
+
+// SetPreferFresher makes SaveCurrent keep a cached observation that is
+// more recent than the one being saved.
+func (s *InMemoryStore) SetPreferFresher(prefer bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	key := normalizeCity(city)
+	s.preferFresher = prefer
+}
 
-	s.current[key] = w
+// SaveCurrent stores latest current weather for a city, updates last fetch time
+// and appends entry to the history with a bounded size. It returns the
+// value now cached.
+// With SetPreferFresher, an observation older than the cached one is not
+// stored: only the fetch time of the cached entry advances. Zero
+// ObservedAt values are never considered older.
+func (s *InMemoryStore) SaveCurrent(city string, w weather.CurrentWeather) weather.CurrentWeather {
+	fetchedAt := s.clock.Now().UTC()
+
+	s.mu.Lock()
+
+	key := weather.NormalizeCity(city)
 	s.lastFetch[key] = fetchedAt
+	s.nameLocked(key, city)
 
 	h := s.currentHistory[key]
-	h = append(h, CurrentSnapshot{
+	if cached, ok := s.current[key]; ok && s.preferFresher && len(h) > 0 &&
+		!w.ObservedAt.IsZero() && w.ObservedAt.Before(cached.ObservedAt) {
+		// The latest snapshot holds the cached value; refresh its fetch
+		// time instead of appending a duplicate.
+		h[len(h)-1].At = fetchedAt
+		s.mu.Unlock()
+
+		slog.Warn("keeping cached observation, fetched one is older",
+			"city", city,
+			"cached_observed_at", cached.ObservedAt,
+			"fetched_observed_at", w.ObservedAt,
+		)
+		s.publish(Update{City: city, At: fetchedAt})
+		return cached
+	}
+
+	s.current[key] = w
+	s.currentHistory[key] = appendBounded(h, CurrentSnapshot{
 		At:   fetchedAt,
 		Data: w,
-	})
-	if len(h) > maxHistoryEntries {
-		h = h[len(h)-maxHistoryEntries:]
-	}
-	s.currentHistory[key] = h
+	}, maxHistoryEntries)
+
+	s.mu.Unlock()
+
+	s.publish(Update{City: city, At: fetchedAt})
+	return w
 }
 
 // GetCurrent returns latest current weather for a city if present.
@@ -77,37 +143,64 @@ func (s *InMemoryStore) GetCurrent(city string) (weather.CurrentWeather, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	w, ok := s.current[normalizeCity(city)]
+	w, ok := s.current[weather.NormalizeCity(city)]
 	return w, ok
 }
 
+// GetCurrentEntry returns latest current weather for a city together
+// with the time it was fetched, regardless of its age.
+func (s *InMemoryStore) GetCurrentEntry(city string) (CurrentSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := weather.NormalizeCity(city)
+	h := s.currentHistory[key]
+	if len(h) == 0 {
+		return CurrentSnapshot{}, false
+	}
+	return h[len(h)-1], true
+}
+
 // SaveForecast stores latest forecast for a city and number of days,
 // updates last fetch time and appends entry to the history
-// with a bounded size.
-func (s *InMemoryStore) SaveForecast(city string, days int, f weather.Forecast, fetchedAt time.Time) {
+// with a bounded size. Latest forecasts of the city over fewer days are
+// dropped, since the new one covers them and is fresher; their history
+// is kept. Synthetic forecasts are not stored: they only stand in for a
+// failed fetch and would otherwise be served from the cache in place of
+// a real forecast.
+func (s *InMemoryStore) SaveForecast(city string, days int, f weather.Forecast) {
+	if f.Synthetic() {
+		return
+	}
+	fetchedAt := s.clock.Now().UTC()
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
-	normalizedCity := normalizeCity(city)
+	normalizedCity := weather.NormalizeCity(city)
 
 	key := forecastKey{
 		City: normalizedCity,
 		Days: days,
 	}
 
+	for k := range s.forecast {
+		if k.City == normalizedCity && k.Days < days {
+			delete(s.forecast, k)
+		}
+	}
 	s.forecast[key] = f
 	s.lastFetch[normalizedCity] = fetchedAt
+	s.nameLocked(normalizedCity, city)
 
-	h := s.forecastHistory[key]
-	h = append(h, ForecastSnapshot{
+	s.forecastHistory[key] = appendBounded(s.forecastHistory[key], ForecastSnapshot{
 		At:   fetchedAt,
 		Days: days,
 		Data: f,
-	})
-	if len(h) > maxHistoryEntries {
-		h = h[len(h)-maxHistoryEntries:]
-	}
-	s.forecastHistory[key] = h
+	}, maxHistoryEntries)
+
+	s.mu.Unlock()
+
+	s.publish(Update{City: city, Days: days, At: fetchedAt})
 }
 
 // GetForecast returns latest forecast for a city and days if present.
@@ -116,7 +209,7 @@ func (s *InMemoryStore) GetForecast(city string, days int) (weather.Forecast, bo
 	defer s.mu.RUnlock()
 
 	key := forecastKey{
-		City: normalizeCity(city),
+		City: weather.NormalizeCity(city),
 		Days: days,
 	}
 
@@ -124,6 +217,23 @@ func (s *InMemoryStore) GetForecast(city string, days int) (weather.Forecast, bo
 	return f, ok
 }
 
+// GetForecastEntry returns latest forecast for a city and days together
+// with the time it was fetched, regardless of its age.
+func (s *InMemoryStore) GetForecastEntry(city string, days int) (ForecastSnapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := forecastKey{
+		City: weather.NormalizeCity(city),
+		Days: days,
+	}
+	h := s.forecastHistory[key]
+	if _, ok := s.forecast[key]; !ok || len(h) == 0 {
+		return ForecastSnapshot{}, false
+	}
+	return h[len(h)-1], true
+}
+
 // CurrentHistory returns up to `limit` recent current weather snapshots
 // for the given city. If limit <= 0 or greater than available entries,
 // all entries are returned.
@@ -131,7 +241,7 @@ func (s *InMemoryStore) CurrentHistory(city string, limit int) []CurrentSnapshot
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	key := normalizeCity(city)
+	key := weather.NormalizeCity(city)
 	h := s.currentHistory[key]
 
 	if len(h) == 0 {
@@ -157,7 +267,7 @@ func (s *InMemoryStore) ForecastHistory(city string, days, limit int) []Forecast
 	defer s.mu.RUnlock()
 
 	key := forecastKey{
-		City: normalizeCity(city),
+		City: weather.NormalizeCity(city),
 		Days: days,
 	}
 	h := s.forecastHistory[key]
@@ -189,7 +299,206 @@ func (s *InMemoryStore) LastFetchTimes() map[string]time.Time {
 	return res
 }
 
-// normalizeCity makes city key consistent (case-insensitive).
-func normalizeCity(city string) string {
-	return strings.ToLower(strings.TrimSpace(city))
+// Cities returns the sorted, distinct display names of all cities with
+// current, forecast or history data: the name each was first saved under.
+func (s *InMemoryStore) Cities() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]struct{}, len(s.current))
+	for city := range s.current {
+		seen[city] = struct{}{}
+	}
+	for key := range s.forecast {
+		seen[key.City] = struct{}{}
+	}
+	for city := range s.currentHistory {
+		seen[city] = struct{}{}
+	}
+	for key := range s.forecastHistory {
+		seen[key.City] = struct{}{}
+	}
+
+	res := make([]string, 0, len(seen))
+	for key := range seen {
+		res = append(res, s.displayNameLocked(key))
+	}
+	slices.Sort(res)
+	return res
+}
+
+// CacheInfo describes what is cached for a city without the payloads.
+type CacheInfo struct {
+	// City is the display name of the city, empty if nothing is cached.
+	City string
+	// Current is the fetch time of cached current weather, nil if absent.
+	Current *time.Time
+	// Forecasts maps each cached forecast days key to its fetch time.
+	Forecasts map[int]time.Time
+}
+
+// Inspect returns cache metadata for a city.
+func (s *InMemoryStore) Inspect(city string) CacheInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key := weather.NormalizeCity(city)
+	info := CacheInfo{
+		City:      s.names[key],
+		Forecasts: make(map[int]time.Time),
+	}
+
+	if h := s.currentHistory[key]; len(h) > 0 {
+		at := h[len(h)-1].At
+		info.Current = &at
+	}
+
+	for k := range s.forecast {
+		if h := s.forecastHistory[k]; k.City == key && len(h) > 0 {
+			info.Forecasts[k.Days] = h[len(h)-1].At
+		}
+	}
+
+	return info
+}
+
+// Evict removes all cached data for a city, including its history and
+// last fetch time. It reports whether anything was removed.
+func (s *InMemoryStore) Evict(city string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.evictLocked(weather.NormalizeCity(city))
+}
+
+// evictLocked removes everything stored for the normalized city key and
+// reports whether anything was found. s.mu must be held.
+func (s *InMemoryStore) evictLocked(key string) bool {
+	_, found := s.lastFetch[key]
+
+	delete(s.current, key)
+	delete(s.lastFetch, key)
+	delete(s.currentHistory, key)
+	delete(s.names, key)
+
+	for k := range s.forecast {
+		if k.City == key {
+			delete(s.forecast, k)
+			found = true
+		}
+	}
+	for k := range s.forecastHistory {
+		if k.City == key {
+			delete(s.forecastHistory, k)
+		}
+	}
+
+	return found
+}
+
+// Sweep removes history snapshots fetched before cutoff. Latest values
+// whose newest snapshot is older go too, and cities left without data
+// are forgotten, so ad-hoc cities no one asks for again do not pile up.
+func (s *InMemoryStore) Sweep(cutoff time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, h := range s.currentHistory {
+		kept := sweepHistory(h, cutoff, func(e CurrentSnapshot) time.Time { return e.At })
+		removed += len(h) - len(kept)
+		if len(kept) == 0 {
+			delete(s.currentHistory, key)
+			delete(s.current, key)
+			continue
+		}
+		s.currentHistory[key] = kept
+	}
+	for key, h := range s.forecastHistory {
+		kept := sweepHistory(h, cutoff, func(e ForecastSnapshot) time.Time { return e.At })
+		removed += len(h) - len(kept)
+		if len(kept) == 0 {
+			delete(s.forecastHistory, key)
+			delete(s.forecast, key)
+			continue
+		}
+		s.forecastHistory[key] = kept
+	}
+	for city, at := range s.lastFetch {
+		if at.Before(cutoff) {
+			delete(s.lastFetch, city)
+		}
+	}
+	for key := range s.names {
+		if _, ok := s.currentHistory[key]; !ok && !s.hasForecastLocked(key) {
+			delete(s.names, key)
+		}
+	}
+
+	return removed
+}
+
+// hasForecastLocked reports whether any forecast history is kept for the
+// normalized city key. s.mu must be held.
+func (s *InMemoryStore) hasForecastLocked(key string) bool {
+	for k := range s.forecastHistory {
+		if k.City == key {
+			return true
+		}
+	}
+	return false
+}
+
+// sweepHistory drops the entries of the chronological history h fetched
+// before cutoff, in place.
+func sweepHistory[T any](h []T, cutoff time.Time, at func(T) time.Time) []T {
+	i := 0
+	for i < len(h) && at(h[i]).Before(cutoff) {
+		i++
+	}
+	if i == 0 {
+		return h
+	}
+	n := copy(h, h[i:])
+	clear(h[n:])
+	return h[:n]
+}
+
+// Subscribe registers fn to be called after every save.
+func (s *InMemoryStore) Subscribe(fn func(Update)) {
+	s.subsMu.Lock()
+	defer s.subsMu.Unlock()
+
+	s.subs = append(s.subs, fn)
+}
+
+func (s *InMemoryStore) publish(u Update) {
+	s.subsMu.RLock()
+	defer s.subsMu.RUnlock()
+
+	for _, fn := range s.subs {
+		fn(u)
+	}
+}
+
+// Close is a no-op: an in-memory store has nothing to flush.
+func (s *InMemoryStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// appendBounded appends v to h, keeping at most limit newest entries.
+// Once full, entries are shifted within the same backing array, so evicted
+// snapshots are released and the array never grows beyond limit.
+func appendBounded[T any](h []T, v T, limit int) []T {
+	if len(h) < limit {
+		if h == nil {
+			h = make([]T, 0, limit)
+		}
+		return append(h, v)
+	}
+
+	copy(h, h[len(h)-limit+1:])
+	h = h[:limit]
+	h[limit-1] = v
+	return h
 }
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+func TestAppendBounded(t *testing.T) {
+	var h []int
+	for i := range 5 {
+		h = appendBounded(h, i, 3)
+	}
+
+	want := []int{2, 3, 4}
+	if len(h) != len(want) {
+		t.Fatalf("got %v, want %v", h, want)
+	}
+	for i := range want {
+		if h[i] != want[i] {
+			t.Fatalf("got %v, want %v", h, want)
+		}
+	}
+	if cap(h) != 3 {
+		t.Errorf("cap = %d, want 3", cap(h))
+	}
+}
+
+func BenchmarkAppendBounded(b *testing.B) {
+	var h []CurrentSnapshot
+	b.ReportAllocs()
+
+	for b.Loop() {
+		h = appendBounded(h, CurrentSnapshot{}, maxHistoryEntries)
+	}
+}
+
+func TestSaveCurrentKeepsFresherObservation(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(t0)
+	s := NewInMemoryStore(clk)
+	s.SetPreferFresher(true)
+
+	newer := weather.CurrentWeather{Temperature: 10, ObservedAt: t0}
+	older := weather.CurrentWeather{Temperature: 7, ObservedAt: t0.Add(-time.Hour)}
+
+	s.SaveCurrent("London", newer)
+	clk.Advance(time.Minute)
+	if got := s.SaveCurrent("london", older); got.Temperature != newer.Temperature {
+		t.Errorf("SaveCurrent returned %+v, want the cached observation", got)
+	}
+
+	if got, _ := s.GetCurrent("London"); got.Temperature != newer.Temperature {
+		t.Errorf("cached %+v, want the fresher observation", got)
+	}
+	h := s.CurrentHistory("London", 0)
+	if len(h) != 1 {
+		t.Fatalf("history has %d snapshots, want 1", len(h))
+	}
+	if entry, _ := s.GetCurrentEntry("London"); !entry.At.Equal(clk.Now()) {
+		t.Errorf("fetch time = %v, want %v", entry.At, clk.Now())
+	}
+}
+
+func TestSaveCurrentOutOfOrderWithoutPreference(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewInMemoryStore(clock.NewManual(t0))
+
+	s.SaveCurrent("London", weather.CurrentWeather{Temperature: 10, ObservedAt: t0})
+	s.SaveCurrent("London", weather.CurrentWeather{Temperature: 7, ObservedAt: t0.Add(-time.Hour)})
+
+	if got, _ := s.GetCurrent("London"); got.Temperature != 7 {
+		t.Errorf("cached %+v, want the last saved observation", got)
+	}
+}
+
+func TestCitiesKeepDisplayName(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s := NewInMemoryStore(clk)
+
+	s.SaveCurrent(" New  York ", weather.CurrentWeather{City: "New York"})
+	s.SaveForecast("NEW YORK", 3, weather.Forecast{City: "New York", Items: []weather.ForecastItem{{TimeStamp: clk.Now()}}})
+
+	if got := s.Cities(); len(got) != 1 || got[0] != "New York" {
+		t.Errorf("Cities() = %q, want [New York]", got)
+	}
+	if got := s.Inspect("new york").City; got != "New York" {
+		t.Errorf("Inspect().City = %q, want New York", got)
+	}
+	if got := s.Export().Cities[0].City; got != "New York" {
+		t.Errorf("exported city = %q, want New York", got)
+	}
+
+	s.Evict("new york")
+	s.SaveCurrent("new york", weather.CurrentWeather{City: "new york"})
+	if got := s.Cities(); len(got) != 1 || got[0] != "new york" {
+		t.Errorf("Cities() after evict = %q, want [new york]", got)
+	}
+
+	clk.Advance(time.Hour)
+	s.Sweep(clk.Now())
+	if got := s.Inspect("new york").City; got != "" {
+		t.Errorf("Inspect().City after sweep = %q, want empty", got)
+	}
+}
+
+func TestSaveForecastDropsShorterLatest(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	s := NewInMemoryStore(clk)
+	forecast := func(days int) weather.Forecast {
+		return weather.Forecast{City: "London", Days: days, Items: []weather.ForecastItem{{TimeStamp: clk.Now()}}}
+	}
+
+	s.SaveForecast("London", 3, forecast(3))
+	s.SaveForecast("London", 10, forecast(10))
+	clk.Advance(time.Minute)
+	s.SaveForecast("London", 7, forecast(7))
+
+	if _, ok := s.GetForecast("London", 3); ok {
+		t.Error("3-day forecast still served after a 7-day one was saved")
+	}
+	if _, ok := s.GetForecastEntry("London", 3); ok {
+		t.Error("3-day entry still served after a 7-day one was saved")
+	}
+	if _, ok := s.GetForecast("London", 10); !ok {
+		t.Error("longer 10-day forecast was dropped")
+	}
+	if got := s.Inspect("London").Forecasts; len(got) != 2 || got[7].IsZero() || got[10].IsZero() {
+		t.Errorf("cache info forecasts = %v, want 7 and 10 days", got)
+	}
+	if got := s.ForecastHistory("London", 3, 0); len(got) != 1 {
+		t.Errorf("3-day history has %d entries, want it kept", len(got))
+	}
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+)
+
+// SweepOlderThan removes data fetched more than period before now and
+// returns the number of snapshots removed and the cutoff applied.
+func SweepOlderThan(s Store, period time.Duration, clk clock.Clock) (int, time.Time) {
+	cutoff := clock.OrReal(clk).Now().UTC().Add(-period)
+	return s.Sweep(cutoff), cutoff
+}
+
+// RunRetention sweeps data older than period from s every interval until
+// ctx is done. If clk is nil, the real clock is used.
+func RunRetention(ctx context.Context, s Store, period, interval time.Duration, clk clock.Clock, log *slog.Logger) {
+	log.Info("retention sweeper started",
+		"period", period.String(),
+		"interval", interval.String(),
+	)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("retention sweeper stopping due to context cancellation")
+			return
+		case <-ticker.C:
+			removed, cutoff := SweepOlderThan(s, period, clk)
+			log.Info("retention sweep finished",
+				"removed", removed,
+				"cutoff", cutoff,
+			)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+// snapshotVersion is the Snapshot format written by Export and accepted
+// by Import.
+const snapshotVersion = 1
+
+// ErrSnapshotVersion is returned by Import for snapshots of a format it
+// does not understand.
+var ErrSnapshotVersion = errors.New("unsupported snapshot version")
+
+// Snapshot is a serializable copy of a store's data, for backups and for
+// seeding a new instance without waiting for the schedulers.
+type Snapshot struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Cities     []CitySnapshot `json:"cities"`
+}
+
+// CitySnapshot holds the history of one city, oldest first; the last
+// entry of each history is the latest value.
+type CitySnapshot struct {
+	City      string             `json:"city"`
+	Current   []CurrentSnapshot  `json:"current,omitempty"`
+	Forecasts []ForecastSnapshot `json:"forecasts,omitempty"`
+}
+
+// ImportResult counts what Import restored and what it dropped as
+// malformed.
+type ImportResult struct {
+	Cities    int `json:"cities"`
+	Current   int `json:"current"`
+	Forecasts int `json:"forecasts"`
+	Dropped   int `json:"dropped"`
+}
+
+// Export returns a snapshot of the whole store, cities sorted by name.
+func (s *InMemoryStore) Export() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cities := make(map[string]*CitySnapshot)
+	city := func(key string) *CitySnapshot {
+		cs, ok := cities[key]
+		if !ok {
+			cs = &CitySnapshot{City: s.displayNameLocked(key)}
+			cities[key] = cs
+		}
+		return cs
+	}
+
+	for key, h := range s.currentHistory {
+		cs := city(key)
+		cs.Current = slices.Clone(h)
+	}
+	for key, h := range s.forecastHistory {
+		cs := city(key.City)
+		cs.Forecasts = append(cs.Forecasts, h...)
+	}
+
+	snap := Snapshot{
+		Version:    snapshotVersion,
+		ExportedAt: s.clock.Now().UTC(),
+		Cities:     make([]CitySnapshot, 0, len(cities)),
+	}
+	for _, cs := range cities {
+		slices.SortStableFunc(cs.Forecasts, func(a, b ForecastSnapshot) int {
+			return cmp.Or(cmp.Compare(a.Days, b.Days), a.At.Compare(b.At))
+		})
+		snap.Cities = append(snap.Cities, *cs)
+	}
+	slices.SortFunc(snap.Cities, func(a, b CitySnapshot) int {
+		return cmp.Compare(a.City, b.City)
+	})
+	return snap
+}
+
+// Import restores snap, replacing the data of every city it contains;
+// other cities are left alone. Entries without a fetch time, with
+// implausible values, or forecasts without items or a valid range are
+// dropped, and histories are trimmed to the newest entries the store
+// keeps. Snapshots of another version are rejected as a whole with
+// ErrSnapshotVersion.
+func (s *InMemoryStore) Import(snap Snapshot) (ImportResult, error) {
+	var res ImportResult
+	if snap.Version != snapshotVersion {
+		return res, fmt.Errorf("%w %d, expected %d", ErrSnapshotVersion, snap.Version, snapshotVersion)
+	}
+
+	var updates []Update
+
+	s.mu.Lock()
+	for _, cs := range snap.Cities {
+		key := weather.NormalizeCity(cs.City)
+		if key == "" {
+			res.Dropped += len(cs.Current) + len(cs.Forecasts)
+			continue
+		}
+
+		current := make([]CurrentSnapshot, 0, len(cs.Current))
+		for _, e := range cs.Current {
+			if e.At.IsZero() || e.Data.Validate() != nil {
+				res.Dropped++
+				continue
+			}
+			current = append(current, e)
+		}
+
+		forecasts := make(map[int][]ForecastSnapshot)
+		for _, e := range cs.Forecasts {
+			if e.At.IsZero() || e.Days < 1 || e.Data.Validate() != nil {
+				res.Dropped++
+				continue
+			}
+			forecasts[e.Days] = append(forecasts[e.Days], e)
+		}
+
+		if len(current) == 0 && len(forecasts) == 0 {
+			continue
+		}
+
+		s.evictLocked(key)
+		s.nameLocked(key, cs.City)
+		res.Cities++
+
+		var lastFetch time.Time
+		if len(current) > 0 {
+			h := restoreHistory(current, func(e CurrentSnapshot) time.Time { return e.At })
+			s.currentHistory[key] = h
+			latest := h[len(h)-1]
+			s.current[key] = latest.Data
+			lastFetch = latest.At
+			res.Current += len(h)
+			updates = append(updates, Update{City: key, At: latest.At})
+		}
+		for days, entries := range forecasts {
+			fk := forecastKey{City: key, Days: days}
+			h := restoreHistory(entries, func(e ForecastSnapshot) time.Time { return e.At })
+			s.forecastHistory[fk] = h
+			latest := h[len(h)-1]
+			s.forecast[fk] = latest.Data
+			if latest.At.After(lastFetch) {
+				lastFetch = latest.At
+			}
+			res.Forecasts += len(h)
+			updates = append(updates, Update{City: key, Days: days, At: latest.At})
+		}
+		s.lastFetch[key] = lastFetch
+	}
+	s.mu.Unlock()
+
+	for _, u := range updates {
+		s.publish(u)
+	}
+	return res, nil
+}
+
+// restoreHistory sorts imported entries chronologically and keeps the
+// newest maxHistoryEntries.
+func restoreHistory[T any](entries []T, at func(T) time.Time) []T {
+	slices.SortStableFunc(entries, func(a, b T) int {
+		return at(a).Compare(at(b))
+	})
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+	return slices.Clip(entries)
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+// Store keeps latest and historical weather data.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// SaveCurrent stores w and returns the value now cached, which is the
+	// previously cached one if the store keeps fresher observations.
+	SaveCurrent(city string, w weather.CurrentWeather) weather.CurrentWeather
+	GetCurrent(city string) (weather.CurrentWeather, bool)
+	GetCurrentEntry(city string) (CurrentSnapshot, bool)
+
+	SaveForecast(city string, days int, f weather.Forecast)
+	GetForecast(city string, days int) (weather.Forecast, bool)
+	GetForecastEntry(city string, days int) (ForecastSnapshot, bool)
+
+	CurrentHistory(city string, limit int) []CurrentSnapshot
+	ForecastHistory(city string, days, limit int) []ForecastSnapshot
+	LastFetchTimes() map[string]time.Time
+	Cities() []string
+
+	Inspect(city string) CacheInfo
+	Evict(city string) bool
+
+	// Sweep removes snapshots fetched before cutoff, including latest
+	// values, and returns how many were removed.
+	Sweep(cutoff time.Time) int
+
+	// Export returns a serializable copy of all data; Import restores
+	// one, replacing the data of the cities it contains.
+	Export() Snapshot
+	Import(snap Snapshot) (ImportResult, error)
+
+	// Subscribe registers fn to be called after every save. Calls happen
+	// synchronously on the saving goroutine, outside the store's locks.
+	Subscribe(fn func(Update))
+
+	// Close flushes pending writes and releases resources. It is called
+	// once on shutdown; the store must not be used afterwards.
+	Close(ctx context.Context) error
+}
+
+var _ Store = (*InMemoryStore)(nil)
+
+// Update describes a save; Days is 0 for current weather.
+type Update struct {
+	City string
+	Days int
+	At   time.Time
+}
+
+// selfTestCity is the sentinel city used by SelfTest.
+const selfTestCity = "__store_selftest__"
+
+// ErrSelfTestFailed is returned when the store does not round-trip data.
+var ErrSelfTestFailed = errors.New("store self-test failed")
+
+// SelfTest saves a sentinel current weather entry, reads it back and
+// compares it with the original. The sentinel is evicted afterwards.
+func SelfTest(s Store) error {
+	want := weather.CurrentWeather{
+		City:        selfTestCity,
+		Temperature: 21.5,
+		Humidity:    42,
+		WindSpeed:   3.25,
+		Description: "self-test",
+		Condition:   weather.ConditionClear,
+		Source:      weather.SourceOpenMeteo,
+		Sources:     []weather.Source{weather.SourceOpenMeteo},
+		ObservedAt:  time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	s.SaveCurrent(selfTestCity, want)
+	defer s.Evict(selfTestCity)
+
+	got, ok := s.GetCurrent(selfTestCity)
+	if !ok {
+		return fmt.Errorf("%w: sentinel not found after save", ErrSelfTestFailed)
+	}
+
+	if !got.ObservedAt.Equal(want.ObservedAt) {
+		return fmt.Errorf("%w: observed_at mismatch: got %s, want %s",
+			ErrSelfTestFailed, got.ObservedAt, want.ObservedAt)
+	}
+	got.ObservedAt = want.ObservedAt
+
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%w: got %+v, want %+v", ErrSelfTestFailed, got, want)
+	}
+
+	return nil
+}
@@ -1,29 +1,322 @@
 package weather
 
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// Plausible value ranges. Provider inputs outside of them are considered
+// broken and excluded from aggregation.
+const (
+	minTemperature = -90.0 // Celsius
+	maxTemperature = 60.0  // Celsius
+	minHumidity    = 0     // %
+	maxHumidity    = 100   // %
+	minWindSpeed   = 0.0   // m/s
+)
+
 // AggregateCurrentWeather combines multiple CurrentWeather results into one.
 //
-// For now it returns the first successful entry. Later this function can be
-// extended to compute averages for temperature, humidity, wind speed and other
-// numeric fields, as well as to merge metadata (sources, confidence, etc.).
-func AggregateCurrentWeather(results []CurrentWeather) CurrentWeather {
+// For now it returns the first successful entry with the most-voted condition
+// code, the strongest wind gust and the latest ObservedAt reported by any
+// input. Inputs without a condition code vote with the one their
+// description maps to. When several inputs contribute, Description is the
+// canonical text of the winning code rather than one provider's wording,
+// and RawConditionCode is taken from the first input that voted for it.
+// The Service passes results ordered by source priority, so the first
+// entry is the most trusted source that answered. Later this function can be extended to compute
+// averages for temperature, humidity, wind speed and other numeric fields,
+// as well as to merge metadata (confidence, etc.).
+//
+// Sources lists every input that contributed; when there is more than one,
+// Source is set to SourceAggregated.
+//
+// Inputs with implausible values are dropped, as are inputs resolved to a
+// different place than the majority (see sameLocation), and listed in
+// Quality; ErrProviderUnavailable is returned if no valid input remains.
+func AggregateCurrentWeather(results []CurrentWeather) (CurrentWeather, error) {
+	results, excluded := validCurrent(results)
+
 	if len(results) == 0 {
-		return CurrentWeather{}
+		return CurrentWeather{}, ErrProviderUnavailable
+	}
+
+	agg := firstCurrent(results)
+	agg.Quality = qualityOf(excluded)
+	return agg, nil
+}
+
+// validCurrent drops implausible results and those outside the majority
+// location cluster, returning why each was dropped.
+func validCurrent(results []CurrentWeather) ([]CurrentWeather, []Exclusion) {
+	var excluded []Exclusion
+	valid := make([]CurrentWeather, 0, len(results))
+	for _, r := range results {
+		if err := validateReading(r.Temperature, r.Humidity, r.WindSpeed); err != nil {
+			slog.Warn("dropping implausible current weather",
+				"source", r.Source,
+				"city", r.City,
+				"error", err,
+			)
+			excluded = append(excluded, Exclusion{Source: r.Source, Reason: ExclusionOutOfRange, Detail: err.Error()})
+			continue
+		}
+		valid = append(valid, r)
+	}
+
+	valid, dropped := sameLocation(valid, func(w CurrentWeather) (Source, *Coordinates) {
+		return w.Source, w.Coordinates
+	})
+	return valid, append(excluded, dropped...)
+}
+
+// firstCurrent returns the first result with the most-voted condition,
+// the strongest gust, the freshest observation time and the sources of
+// all results. Gusts are peaks, so they are never averaged.
+func firstCurrent(results []CurrentWeather) CurrentWeather {
+	agg := results[0]
+
+	codes := make([]ConditionCode, 0, len(results))
+	sources := make([]Source, 0, len(results))
+	for _, r := range results {
+		codes = append(codes, conditionOf(r.Condition, r.Description))
+		sources = appendSource(sources, r.Source)
+		agg.WindGust = max(agg.WindGust, r.WindGust)
+		if r.ObservedAt.After(agg.ObservedAt) {
+			agg.ObservedAt = r.ObservedAt
+		}
+	}
+	agg.Condition = dominantCondition(codes)
+	agg.Sources = sources
+	if len(sources) > 1 {
+		agg.Source = SourceAggregated
+		agg.Description = mergedDescription(agg.Condition, agg.Language, agg.Description)
+		// Native codes are provider-specific; report one matching the
+		// condition rather than the first input's.
+		agg.RawConditionCode = 0
+		if i := slices.Index(codes, agg.Condition); i >= 0 {
+			agg.RawConditionCode = results[i].RawConditionCode
+		}
 	}
 
-	// TODO: implement real aggregation logic (averages, merge sources, etc.).
-	return results[0]
+	return agg
 }
 
 // AggregateForecast combines multiple Forecast results into one.
 //
-// For now it returns the first successful entry. Later this function can be
-// extended to merge time series, deduplicate timestamps, and average numeric
-// values across providers.
-func AggregateForecast(results []Forecast) Forecast {
+// For now it returns the first successful entry, i.e. the one from the
+// highest-priority source, with Sources derived from its items. Source is
+// the single contributing source, or SourceAggregated if there are more. Later this
+// function can be extended to merge time series, deduplicate timestamps,
+// and average numeric values across providers.
+//
+// Forecasts without items, containing implausible values or resolved to a
+// different place than the majority are dropped and listed in Quality;
+// ErrProviderUnavailable is returned if no valid input remains.
+func AggregateForecast(results []Forecast) (Forecast, error) {
+	results, excluded := validForecasts(results)
+
 	if len(results) == 0 {
-		return Forecast{}
+		return Forecast{}, ErrProviderUnavailable
+	}
+
+	agg := results[0]
+	agg.ActualDays = DistinctDays(agg.Items)
+
+	var sources []Source
+	for _, it := range agg.Items {
+		sources = appendSource(sources, it.Source)
+	}
+	agg.Sources = sources
+	agg.Source = forecastSource(agg.Source, sources)
+	agg.Quality = qualityOf(excluded)
+
+	return agg, nil
+}
+
+// forecastSource returns the Source of a forecast built from sources:
+// SourceAggregated for several, the only one for one, and fallback if
+// items carry no source.
+func forecastSource(fallback Source, sources []Source) Source {
+	switch len(sources) {
+	case 0:
+		return fallback
+	case 1:
+		return sources[0]
+	default:
+		return SourceAggregated
 	}
+}
+
+// validForecasts drops forecasts without items, with implausible values
+// or outside the majority location cluster, returning why each was dropped.
+func validForecasts(results []Forecast) ([]Forecast, []Exclusion) {
+	var excluded []Exclusion
+	valid := make([]Forecast, 0, len(results))
+	for _, r := range results {
+		if len(r.Items) == 0 {
+			slog.Warn("dropping empty forecast",
+				"city", r.City,
+				"days", r.Days,
+			)
+			excluded = append(excluded, Exclusion{Source: r.Source, Reason: ExclusionEmpty})
+			continue
+		}
+		if err := validateForecast(r); err != nil {
+			slog.Warn("dropping implausible forecast",
+				"city", r.City,
+				"error", err,
+			)
+			excluded = append(excluded, Exclusion{Source: r.Source, Reason: ExclusionOutOfRange, Detail: err.Error()})
+			continue
+		}
+		valid = append(valid, r)
+	}
+
+	valid, dropped := sameLocation(valid, func(f Forecast) (Source, *Coordinates) {
+		return f.Source, f.Coordinates
+	})
+	return valid, append(excluded, dropped...)
+}
+
+// Validate reports whether w holds plausible values, e.g. before
+// restoring it from a backup.
+func (w CurrentWeather) Validate() error {
+	return validateReading(w.Temperature, w.Humidity, w.WindSpeed)
+}
 
-	// TODO: implement real aggregation logic when multiple providers are live.
-	return results[0]
+// Validate reports whether f has items, all with plausible values.
+func (f Forecast) Validate() error {
+	if len(f.Items) == 0 {
+		return errors.New("forecast has no items")
+	}
+	return validateForecast(f)
+}
+
+// validateReading checks that numeric values are within plausible ranges.
+func validateReading(temperature float64, humidity int, windSpeed float64) error {
+	if temperature < minTemperature || temperature > maxTemperature {
+		return fmt.Errorf("temperature %.1f out of range [%.0f, %.0f]",
+			temperature, minTemperature, maxTemperature)
+	}
+	if humidity < minHumidity || humidity > maxHumidity {
+		return fmt.Errorf("humidity %d out of range [%d, %d]",
+			humidity, minHumidity, maxHumidity)
+	}
+	if windSpeed < minWindSpeed {
+		return fmt.Errorf("wind speed %.1f below %.0f", windSpeed, minWindSpeed)
+	}
+	return nil
+}
+
+// validateForecast checks every forecast item, attributing errors to the
+// item's source and timestamp.
+func validateForecast(f Forecast) error {
+	for _, it := range f.Items {
+		if err := validateReading(it.Temperature, it.Humidity, it.WindSpeed); err != nil {
+			return fmt.Errorf("%s item at %s: %w", it.Source, it.TimeStamp.Format("2006-01-02T15:04Z07:00"), err)
+		}
+	}
+	return nil
+}
+
+// appendSource appends src to sources unless it is empty or already listed.
+func appendSource(sources []Source, src Source) []Source {
+	if src == "" || slices.Contains(sources, src) {
+		return sources
+	}
+	return append(sources, src)
+}
+
+// conditionOf returns code, or the condition description maps to when
+// the provider left code unset.
+func conditionOf(code ConditionCode, description string) ConditionCode {
+	if code == "" || code == ConditionUnknown {
+		return ConditionFromText(description)
+	}
+	return code
+}
+
+// mergedDescription returns the description of a value merged from
+// several sources: the canonical text of condition, so that synonyms
+// such as "few clouds" and "scattered clouds" read the same. Canonical
+// texts are English, so fallback is kept for other languages and for
+// unknown conditions.
+func mergedDescription(condition ConditionCode, lang, fallback string) string {
+	if lang != "" && lang != DefaultLanguage {
+		return fallback
+	}
+	if d := condition.Description(); d != "" {
+		return d
+	}
+	return fallback
+}
+
+// dominantCondition returns the most frequent known condition code.
+// Ties go to the code seen first, i.e. the one of the highest-priority
+// source when codes are in source priority order.
+func dominantCondition(codes []ConditionCode) ConditionCode {
+	// There are only a handful of distinct codes: count them in a small
+	// stack-allocated table instead of a map, as this runs per forecast item.
+	type count struct {
+		code ConditionCode
+		n    int
+	}
+	var buf [16]count
+	counts := buf[:0]
+
+	for _, c := range codes {
+		if c == "" || c == ConditionUnknown {
+			continue
+		}
+		i := slices.IndexFunc(counts, func(x count) bool { return x.code == c })
+		if i < 0 {
+			i = len(counts)
+			counts = append(counts, count{code: c})
+		}
+		counts[i].n++
+	}
+
+	// counts is in order of first appearance, so the strict comparison
+	// keeps the earliest code on ties.
+	best := ConditionUnknown
+	bestCount := 0
+	for _, x := range counts {
+		if x.n > bestCount {
+			best, bestCount = x.code, x.n
+		}
+	}
+
+	return best
+}
+
+// SyntheticForecast builds a single-item forecast from current weather,
+// for when no forecast provider succeeded. It is marked with
+// SourceSynthetic; Sources lists the providers of the reading.
+func SyntheticForecast(cw CurrentWeather, days int) Forecast {
+	return Forecast{
+		City: cw.City,
+		Items: []ForecastItem{{
+			TimeStamp:        cw.ObservedAt,
+			Temperature:      cw.Temperature,
+			Humidity:         cw.Humidity,
+			WindSpeed:        cw.WindSpeed,
+			WindGust:         cw.WindGust,
+			WindDirection:    cw.WindDirection,
+			Description:      cw.Description,
+			Condition:        cw.Condition,
+			RawConditionCode: cw.RawConditionCode,
+			Source:           SourceSynthetic,
+		}},
+		Days:        days,
+		ActualDays:  1,
+		Source:      SourceSynthetic,
+		Sources:     slices.Clone(cw.Sources),
+		Aggregation: AggregationSynthetic,
+		Language:    cw.Language,
+		Coordinates: cw.Coordinates,
+		UpdatedAt:   cw.ObservedAt,
+	}
 }
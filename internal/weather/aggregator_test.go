@@ -0,0 +1,166 @@
+package weather_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+func TestAggregateCurrentWeatherRawConditionCode(t *testing.T) {
+	first := reading(weather.SourceOpenMeteo, 10, weather.ConditionClear).current
+	first.Source, first.RawConditionCode = weather.SourceOpenMeteo, 0
+	second := reading(weather.SourceOpenWeather, 10, weather.ConditionRain).current
+	second.Source, second.RawConditionCode = weather.SourceOpenWeather, 500
+	third := reading(weather.SourceWeatherAPI, 10, weather.ConditionRain).current
+	third.Source, third.RawConditionCode = weather.SourceWeatherAPI, 1183
+
+	agg, err := weather.AggregateCurrentWeather([]weather.CurrentWeather{first, second, third})
+	if err != nil {
+		t.Fatalf("AggregateCurrentWeather: %v", err)
+	}
+
+	if agg.Condition != weather.ConditionRain {
+		t.Fatalf("condition = %s, want rain", agg.Condition)
+	}
+	if agg.RawConditionCode != 500 {
+		t.Errorf("raw condition code = %d, want 500 from the first input voting rain", agg.RawConditionCode)
+	}
+}
+
+func TestAggregateCurrentWeatherSingleSourceKeepsRawCode(t *testing.T) {
+	w := reading(weather.SourceOpenMeteo, 10, weather.ConditionRain).current
+	w.Source, w.RawConditionCode = weather.SourceOpenMeteo, 61
+
+	agg, err := weather.AggregateCurrentWeather([]weather.CurrentWeather{w})
+	if err != nil {
+		t.Fatalf("AggregateCurrentWeather: %v", err)
+	}
+	if agg.RawConditionCode != 61 {
+		t.Errorf("raw condition code = %d, want 61", agg.RawConditionCode)
+	}
+}
+
+func TestAggregateCurrentWeatherMergesSynonyms(t *testing.T) {
+	var results []weather.CurrentWeather
+	for _, r := range []struct {
+		source weather.Source
+		desc   string
+	}{
+		{weather.SourceOpenMeteo, "partly cloudy"},
+		{weather.SourceOpenWeather, "few clouds"},
+		{weather.SourceWeatherAPI, "scattered clouds"},
+	} {
+		w := reading(r.source, 10, "").current
+		w.Source, w.Description = r.source, r.desc
+		results = append(results, w)
+	}
+
+	for name, aggregate := range map[string]func([]weather.CurrentWeather) (weather.CurrentWeather, error){
+		"first": weather.AggregateCurrentWeather,
+		"weighted": func(r []weather.CurrentWeather) (weather.CurrentWeather, error) {
+			return weather.AggregateCurrentWeatherWeighted(r, nil)
+		},
+	} {
+		agg, err := aggregate(results)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if agg.Condition != weather.ConditionPartlyCloudy {
+			t.Errorf("%s: condition = %s, want %s", name, agg.Condition, weather.ConditionPartlyCloudy)
+		}
+		if want := weather.ConditionPartlyCloudy.Description(); agg.Description != want {
+			t.Errorf("%s: description = %q, want %q", name, agg.Description, want)
+		}
+	}
+}
+
+func TestAggregateCurrentWeatherTieFollowsPriority(t *testing.T) {
+	rain := reading(weather.SourceOpenMeteo, 10, weather.ConditionRain)
+	cloudy := reading(weather.SourceOpenWeather, 10, weather.ConditionCloudy)
+	providers := []weather.Provider{rain, cloudy}
+
+	for _, tc := range []struct {
+		priority []weather.Source
+		want     weather.ConditionCode
+	}{
+		{[]weather.Source{weather.SourceOpenMeteo, weather.SourceOpenWeather}, weather.ConditionRain},
+		{[]weather.Source{weather.SourceOpenWeather, weather.SourceOpenMeteo}, weather.ConditionCloudy},
+	} {
+		svc := weather.NewService(providers, clock.NewManual(testNow), weather.WithSourcePriority(tc.priority))
+		w, err := svc.GetCurrentWeather(context.Background(), "London")
+		if err != nil {
+			t.Fatalf("GetCurrentWeather: %v", err)
+		}
+		if w.Condition != tc.want {
+			t.Errorf("priority %v: condition = %s, want %s", tc.priority, w.Condition, tc.want)
+		}
+		if want := tc.want.Description(); w.Description != want {
+			t.Errorf("priority %v: description = %q, want %q", tc.priority, w.Description, want)
+		}
+	}
+}
+
+// windReadings returns current weather from three sources with different
+// sustained winds and gusts.
+func windReadings() []weather.CurrentWeather {
+	var res []weather.CurrentWeather
+	for _, r := range []struct {
+		source      weather.Source
+		speed, gust float64
+	}{
+		{weather.SourceOpenMeteo, 3, 9},
+		{weather.SourceOpenWeather, 6, 14},
+		{weather.SourceWeatherAPI, 9, 11},
+	} {
+		w := reading(r.source, 10, weather.ConditionClear).current
+		w.Source, w.WindSpeed, w.WindGust = r.source, r.speed, r.gust
+		res = append(res, w)
+	}
+	return res
+}
+
+func TestAggregateCurrentWeatherWind(t *testing.T) {
+	agg, err := weather.AggregateCurrentWeatherWeighted(windReadings(), nil)
+	if err != nil {
+		t.Fatalf("AggregateCurrentWeatherWeighted: %v", err)
+	}
+	if agg.WindSpeed != 6 {
+		t.Errorf("wind speed = %v, want the mean 6", agg.WindSpeed)
+	}
+	if agg.WindGust != 14 {
+		t.Errorf("wind gust = %v, want the max 14", agg.WindGust)
+	}
+
+	// The first strategy keeps the top source's sustained wind, but
+	// gusts are still the strongest reported.
+	agg, err = weather.AggregateCurrentWeather(windReadings())
+	if err != nil {
+		t.Fatalf("AggregateCurrentWeather: %v", err)
+	}
+	if agg.WindSpeed != 3 || agg.WindGust != 14 {
+		t.Errorf("wind = %v gusting %v, want 3 gusting 14", agg.WindSpeed, agg.WindGust)
+	}
+}
+
+func TestAggregateForecastWeightedWind(t *testing.T) {
+	var forecasts []weather.Forecast
+	for _, w := range windReadings() {
+		f := hourlyForecast(w.Source, 2, 10)
+		for i := range f.Items {
+			f.Items[i].WindSpeed, f.Items[i].WindGust = w.WindSpeed, w.WindGust
+		}
+		forecasts = append(forecasts, f)
+	}
+
+	agg, err := weather.AggregateForecastWeighted(forecasts, nil)
+	if err != nil {
+		t.Fatalf("AggregateForecastWeighted: %v", err)
+	}
+	for i, it := range agg.Items {
+		if it.WindSpeed != 6 || it.WindGust != 14 {
+			t.Errorf("item %d wind = %v gusting %v, want the mean 6 gusting the max 14", i, it.WindSpeed, it.WindGust)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package weather
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Budget describes a provider's upstream rate-limit budget for the
+// current window.
+type Budget struct {
+	Limit     int       // calls allowed per window; 0 if unknown
+	Used      int       // calls made by this instance in the window
+	Remaining int       // calls left; meaningful only if Limited
+	Limited   bool      // whether Remaining is known
+	ResetAt   time.Time // end of the window
+}
+
+// Exhausted reports whether no calls are left in the window.
+func (b Budget) Exhausted() bool {
+	return b.Limited && b.Remaining <= 0
+}
+
+// BudgetReporter is implemented by providers that track their upstream
+// rate-limit budget. The Service skips providers whose budget is
+// exhausted until the window resets.
+type BudgetReporter interface {
+	Budget() Budget
+}
+
+// BudgetOf returns the budget reported by p, if it reports one.
+func BudgetOf(p Provider) (Budget, bool) {
+	if br, ok := p.(BudgetReporter); ok {
+		return br.Budget(), true
+	}
+	return Budget{}, false
+}
+
+// Rate-limit headers sent by some upstreams, the draft IETF names
+// first. Values reported by the upstream win over local counting.
+var (
+	remainingHeaders = []string{"RateLimit-Remaining", "X-RateLimit-Remaining"}
+	limitHeaders     = []string{"RateLimit-Limit", "X-RateLimit-Limit"}
+)
+
+// quota counts upstream calls per UTC day against a declared daily limit,
+// corrected by rate-limit headers when the upstream sends them. It is
+// safe for concurrent use.
+type quota struct {
+	limit int // declared daily limit; 0 if unknown
+
+	mu          sync.Mutex
+	day         time.Time // start of the current window
+	used        int
+	headerLimit int // from headers in this window; 0 if none seen
+	remaining   int // from headers in this window; -1 if none seen
+}
+
+func newQuota(limit int) *quota {
+	return &quota{limit: max(limit, 0), remaining: -1}
+}
+
+// record counts a call answered with resp at now. A 429 without
+// rate-limit headers marks the budget exhausted for the rest of the
+// window.
+func (q *quota) record(resp *http.Response, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.roll(now)
+	q.used++
+	if v, ok := headerInt(resp.Header, remainingHeaders); ok {
+		q.remaining = v
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		q.remaining = 0
+	}
+	if v, ok := headerInt(resp.Header, limitHeaders); ok {
+		q.headerLimit = v
+	}
+}
+
+// budget returns the budget at now.
+func (q *quota) budget(now time.Time) Budget {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.roll(now)
+	b := Budget{
+		Limit:   q.limit,
+		Used:    q.used,
+		ResetAt: q.day.Add(24 * time.Hour),
+	}
+	if q.headerLimit > 0 {
+		b.Limit = q.headerLimit
+	}
+	switch {
+	case q.remaining >= 0:
+		b.Remaining, b.Limited = q.remaining, true
+	case b.Limit > 0:
+		b.Remaining, b.Limited = max(b.Limit-q.used, 0), true
+	}
+	return b
+}
+
+// roll starts a new window if now is past the current one. q.mu must be
+// held.
+func (q *quota) roll(now time.Time) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	if day.Equal(q.day) {
+		return
+	}
+	q.day = day
+	q.used = 0
+	q.headerLimit = 0
+	q.remaining = -1
+}
+
+// headerInt returns the first of names present in h as a non-negative
+// integer.
+func headerInt(h http.Header, names []string) (int, bool) {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			return n, err == nil && n >= 0
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package weather
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// flight is one provider fan-out whose outcome is shared by every call
+// with the same key that starts while it runs, or within the coalescing
+// window after it succeeded.
+type flight[T any] struct {
+	done    chan struct{}
+	val     T
+	report  Report
+	err     error
+	expires time.Time // zero while in flight
+}
+
+// flightGroup collapses concurrent identical Service calls, e.g. a
+// scheduler tick and an API request for the same city, into one fan-out.
+type flightGroup[T any] struct {
+	mu      sync.Mutex
+	flights map[string]*flight[T]
+}
+
+// do returns the outcome of fn for key, running it only if no flight for
+// key is in progress or, after a success, younger than window. Callers
+// joining a flight stop waiting when their own ctx is done, but the
+// fan-out itself runs under the ctx of the call that started it.
+func (g *flightGroup[T]) do(
+	ctx context.Context,
+	key string,
+	window time.Duration,
+	now func() time.Time,
+	fn func() (T, Report, error),
+) (T, Report, error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok && (f.expires.IsZero() || now().Before(f.expires)) {
+		g.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.val, f.report, f.err
+		case <-ctx.Done():
+			var zero T
+			return zero, Report{}, allFailedErr(ctx, ctx.Err())
+		}
+	}
+
+	f := &flight[T]{done: make(chan struct{})}
+	if g.flights == nil {
+		g.flights = make(map[string]*flight[T])
+	}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	f.val, f.report, f.err = fn()
+	close(f.done)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f.err != nil || window <= 0 {
+		g.forget(key, f)
+		return f.val, f.report, f.err
+	}
+	f.expires = now().Add(window)
+	time.AfterFunc(window, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.forget(key, f)
+	})
+	return f.val, f.report, f.err
+}
+
+// forget removes f unless a newer flight replaced it. g.mu must be held.
+func (g *flightGroup[T]) forget(key string, f *flight[T]) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
+
+// flightKey identifies calls that may share a fan-out.
+func flightKey(ctx context.Context, city string, days int) string {
+	return NormalizeCity(city) + "|" + strconv.Itoa(days) + "|" + Language(ctx)
+}
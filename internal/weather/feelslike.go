@@ -0,0 +1,42 @@
+package weather
+
+import "math"
+
+// Bounds of the feels-like formulas. Wind chill is defined for cold air
+// and a perceptible wind, the heat index for hot air; in between the air
+// temperature is what it feels like.
+const (
+	windChillMaxTemp  = 10.0 // °C
+	windChillMinWind  = 4.8  // km/h
+	heatIndexMinTemp  = 27.0 // °C
+	heatIndexMinHumid = 40   // %
+)
+
+// FeelsLike returns the apparent temperature in °C for air at tempC with
+// humidity % and windSpeed m/s: the wind chill (Environment Canada / NWS
+// formula) at or below 10 °C, the heat index (Rothfusz regression) at or
+// above 27 °C with humidity of at least 40%, and tempC otherwise. The
+// result is rounded to one decimal.
+func FeelsLike(tempC float64, humidity int, windSpeed float64) float64 {
+	feels := tempC
+
+	switch {
+	case tempC <= windChillMaxTemp:
+		kmh := windSpeed * 3.6
+		if kmh > windChillMinWind {
+			v := math.Pow(kmh, 0.16)
+			feels = 13.12 + 0.6215*tempC - 11.37*v + 0.3965*tempC*v
+			feels = math.Min(feels, tempC)
+		}
+
+	case tempC >= heatIndexMinTemp && humidity >= heatIndexMinHumid:
+		t := tempC*9/5 + 32
+		rh := float64(humidity)
+		hi := -42.379 + 2.04901523*t + 10.14333127*rh -
+			0.22475541*t*rh - 0.00683783*t*t - 0.05481717*rh*rh +
+			0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+		feels = math.Max((hi-32)*5/9, tempC)
+	}
+
+	return math.Round(feels*10) / 10
+}
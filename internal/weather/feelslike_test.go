@@ -0,0 +1,33 @@
+package weather
+
+import "testing"
+
+func TestFeelsLikeBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		temp     float64
+		humidity int
+		wind     float64 // m/s
+		want     float64
+	}{
+		{"wind chill at 10 °C", 10, 50, 10, 6.2},
+		{"no wind chill above 10 °C", 10.1, 50, 10, 10.1},
+		{"wind chill table value", -10, 50, 20 / 3.6, -17.9},
+		{"wind chill just above 4.8 km/h", 10, 50, 4.9 / 3.6, 9.8},
+		{"no wind chill below 4.8 km/h", 10, 50, 4.7 / 3.6, 10},
+		{"no wind chill in calm air", 0, 50, 0, 0},
+		{"heat index at 27 °C", 27, 90, 0, 30.7},
+		{"no heat index below 27 °C", 26.9, 90, 0, 26.9},
+		{"heat index at 40% humidity", 35, 40, 0, 37.2},
+		{"no heat index below 40% humidity", 35, 39, 0, 35},
+		{"heat index never below air temperature", 27, 40, 0, 27},
+		{"air temperature in between", 20, 90, 10, 20},
+	}
+
+	for _, tt := range tests {
+		if got := FeelsLike(tt.temp, tt.humidity, tt.wind); got != tt.want {
+			t.Errorf("%s: FeelsLike(%v, %d, %v) = %v, want %v",
+				tt.name, tt.temp, tt.humidity, tt.wind, got, tt.want)
+		}
+	}
+}
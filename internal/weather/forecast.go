@@ -0,0 +1,154 @@
+package weather
+
+import (
+	"log/slog"
+	"time"
+)
+
+// NextHours returns up to n forecast items starting from the hour containing
+// from. Items are expected to be ordered by timestamp.
+func NextHours(items []ForecastItem, from time.Time, n int) []ForecastItem {
+	if n <= 0 {
+		return nil
+	}
+
+	start := from.UTC().Truncate(time.Hour)
+	res := make([]ForecastItem, 0, n)
+
+	for _, it := range items {
+		if it.TimeStamp.Before(start) {
+			continue
+		}
+		res = append(res, it)
+		if len(res) == n {
+			break
+		}
+	}
+
+	return res
+}
+
+// DaysForHours returns how many calendar days (UTC) must be fetched to cover
+// the next n hours starting from the hour containing from.
+func DaysForHours(from time.Time, n int) int {
+	hoursLeftToday := 24 - from.UTC().Hour()
+	if n <= hoursLeftToday {
+		return 1
+	}
+	return 1 + (n-hoursLeftToday+23)/24
+}
+
+// DistinctDays returns the number of distinct calendar days (UTC) covered
+// by the items' timestamps.
+func DistinctDays(items []ForecastItem) int {
+	// Sized for days, not items: a week of hourly items spans 7 keys.
+	seen := make(map[time.Time]struct{}, len(items)/24+1)
+	for _, it := range items {
+		seen[it.TimeStamp.UTC().Truncate(24*time.Hour)] = struct{}{}
+	}
+	return len(seen)
+}
+
+// FirstDays returns a copy of f limited to items within the first days
+// distinct calendar days (UTC) starting with the day of from, with Days
+// set to days. It lets a cached longer forecast answer a request for a
+// shorter one: items of days already over when the entry is served are
+// skipped, so an entry fetched before midnight still covers the requested
+// days. UpdatedAt keeps the fetch time of f. A zero from skips nothing.
+func FirstDays(f Forecast, days int, from time.Time) Forecast {
+	items := make([]ForecastItem, 0, len(f.Items))
+	seen := make(map[time.Time]struct{}, days)
+	today := from.UTC().Truncate(24 * time.Hour)
+
+	for _, it := range f.Items {
+		day := it.TimeStamp.UTC().Truncate(24 * time.Hour)
+		if day.Before(today) {
+			continue
+		}
+		if _, ok := seen[day]; !ok {
+			if len(seen) == days {
+				continue
+			}
+			seen[day] = struct{}{}
+		}
+		items = append(items, it)
+	}
+
+	f.Items = items
+	f.Days = days
+	f.ActualDays = len(seen)
+	return f
+}
+
+// withActualDays sets f.ActualDays from its items and warns when the
+// provider covered fewer days than requested.
+func withActualDays(f Forecast, src Source) Forecast {
+	f.ActualDays = DistinctDays(f.Items)
+	if f.ActualDays < f.Days {
+		slog.Warn("provider returned fewer forecast days than requested",
+			"provider", src,
+			"city", f.City,
+			"requested", f.Days,
+			"actual", f.ActualDays,
+		)
+	}
+	return f
+}
+
+// In returns a copy of f with all timestamps converted to loc.
+func (f Forecast) In(loc *time.Location) Forecast {
+	items := make([]ForecastItem, len(f.Items))
+	for i, it := range f.Items {
+		it.TimeStamp = it.TimeStamp.In(loc)
+		items[i] = it
+	}
+	f.Items = items
+	f.UpdatedAt = f.UpdatedAt.In(loc)
+	return f
+}
+
+// DailySummary folds one UTC day of forecast items.
+type DailySummary struct {
+	Date           string        `json:"date"` // YYYY-MM-DD, UTC
+	MinTemperature float64       `json:"min_temperature"`
+	MaxTemperature float64       `json:"max_temperature"`
+	Condition      ConditionCode `json:"condition"` // most frequent in the day
+}
+
+// DailySummaries groups items by UTC day and returns one summary per day,
+// in chronological order. Items are expected to be ordered by timestamp.
+// Temperatures are folded in canonical °C and not rounded; any unit
+// conversion must be applied to the summaries, not to the items before
+// folding, so a converted high matches the converted metric high.
+func DailySummaries(items []ForecastItem) []DailySummary {
+	var (
+		res   []DailySummary
+		codes []ConditionCode
+	)
+
+	for _, it := range items {
+		date := it.TimeStamp.UTC().Format(time.DateOnly)
+
+		if len(res) == 0 || res[len(res)-1].Date != date {
+			if len(res) > 0 {
+				res[len(res)-1].Condition = dominantCondition(codes)
+			}
+			res = append(res, DailySummary{
+				Date:           date,
+				MinTemperature: it.Temperature,
+				MaxTemperature: it.Temperature,
+			})
+			codes = codes[:0]
+		}
+
+		day := &res[len(res)-1]
+		day.MinTemperature = min(day.MinTemperature, it.Temperature)
+		day.MaxTemperature = max(day.MaxTemperature, it.Temperature)
+		codes = append(codes, it.Condition)
+	}
+
+	if len(res) > 0 {
+		res[len(res)-1].Condition = dominantCondition(codes)
+	}
+	return res
+}
@@ -0,0 +1,301 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+)
+
+// Location is a city resolved to geographic coordinates.
+type Location struct {
+	Name    string  `json:"name"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+	Country string  `json:"country,omitempty"` // ISO 3166-1 alpha-2
+}
+
+// Coordinates returns the location's coordinates.
+func (l Location) Coordinates() *Coordinates {
+	return &Coordinates{Lat: l.Lat, Lon: l.Lon}
+}
+
+// CoordinateResolver resolves city names into coordinates.
+type CoordinateResolver interface {
+	// Resolve returns the location of the given city or ErrCityNotFound.
+	Resolve(ctx context.Context, city string) (Location, error)
+}
+
+// NormalizeCity returns the key under which city is looked up and cached:
+// trimmed, with inner whitespace runs collapsed to one space and
+// case-folded, so "New  York" and "NEW YORK" share one entry. Folding goes
+// through upper case to also match letters such as "ſ" and "ς", which
+// strings.ToLower alone keeps distinct.
+func NormalizeCity(city string) string {
+	return strings.ToLower(strings.ToUpper(strings.Join(strings.Fields(city), " ")))
+}
+
+// knownCities is a small, hard-coded city → location map used as a seed,
+// so the default cities never require a geocoding round-trip.
+var knownCities = map[string]Location{
+	"london": {
+		Name:    "London",
+		Lat:     51.5074,
+		Lon:     -0.1278,
+		Country: "GB",
+	},
+	"paris": {
+		Name:    "Paris",
+		Lat:     48.8566,
+		Lon:     2.3522,
+		Country: "FR",
+	},
+	"warsaw": {
+		Name:    "Warsaw",
+		Lat:     52.2297,
+		Lon:     21.0122,
+		Country: "PL",
+	},
+}
+
+// StaticResolver resolves only the hard-coded known cities.
+type StaticResolver struct{}
+
+// Resolve returns the known location of city or ErrCityNotFound.
+func (StaticResolver) Resolve(_ context.Context, city string) (Location, error) {
+	loc, ok := knownCities[NormalizeCity(city)]
+	if !ok {
+		return Location{}, ErrCityNotFound
+	}
+	return loc, nil
+}
+
+// maxGeocodingEntries caps the geocoding cache. Keys are user-supplied
+// city names, so without a cap random queries would grow it forever.
+const maxGeocodingEntries = 10000
+
+// GeocodingResolver resolves cities using the Open-Meteo geocoding API.
+//
+// Both successful and "city not found" results are cached for their own TTL,
+// so repeated requests for a non-existent city do not hammer the API. The
+// cache holds at most maxGeocodingEntries; see store.
+// The number of concurrent lookups is bounded and every lookup has its own
+// timeout, independent of the weather fetch that triggered it.
+type GeocodingResolver struct {
+	client      *http.Client
+	baseURL     string
+	timeout     time.Duration
+	ttl         time.Duration
+	negativeTTL time.Duration
+	clock       clock.Clock
+
+	sem chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]geocodingEntry
+}
+
+type geocodingEntry struct {
+	loc       Location
+	err       error
+	expiresAt time.Time
+}
+
+// NewGeocodingResolver creates a new GeocodingResolver.
+// If client is nil, http.DefaultClient is used; if clk is nil, the real clock
+// is used. A concurrency below 1 is treated as 1.
+func NewGeocodingResolver(
+	client *http.Client,
+	timeout time.Duration,
+	concurrency int,
+	ttl time.Duration,
+	negativeTTL time.Duration,
+	clk clock.Clock,
+) *GeocodingResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &GeocodingResolver{
+		client:      client,
+		baseURL:     "https://geocoding-api.open-meteo.com/v1/search",
+		timeout:     timeout,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		clock:       clock.OrReal(clk),
+		sem:         make(chan struct{}, concurrency),
+		cache:       make(map[string]geocodingEntry),
+	}
+}
+
+// ---- Geocoding DTO ----
+
+type geocodingResponse struct {
+	Results []struct {
+		Name        string  `json:"name"`
+		Latitude    float64 `json:"latitude"`
+		Longitude   float64 `json:"longitude"`
+		CountryCode string  `json:"country_code"`
+	} `json:"results"`
+}
+
+// Resolve returns the location of the given city. Known cities are served
+// from the seed map, others from the cache or the geocoding API.
+func (r *GeocodingResolver) Resolve(ctx context.Context, city string) (Location, error) {
+	key := NormalizeCity(city)
+	if key == "" {
+		return Location{}, ErrCityNotFound
+	}
+
+	if loc, ok := knownCities[key]; ok {
+		return loc, nil
+	}
+
+	if e, ok := r.cached(key); ok {
+		return e.loc, e.err
+	}
+
+	// Bound concurrent lookups.
+	select {
+	case r.sem <- struct{}{}:
+		defer func() { <-r.sem }()
+	case <-ctx.Done():
+		return Location{}, requestError(ctx, ctx.Err())
+	}
+
+	// Another lookup may have filled the cache while we were waiting.
+	if e, ok := r.cached(key); ok {
+		return e.loc, e.err
+	}
+
+	loc, err := r.lookup(ctx, city)
+	switch {
+	case err == nil:
+		r.store(key, geocodingEntry{loc: loc, expiresAt: r.clock.Now().Add(r.ttl)})
+	case errors.Is(err, ErrCityNotFound):
+		r.store(key, geocodingEntry{err: err, expiresAt: r.clock.Now().Add(r.negativeTTL)})
+	}
+
+	return loc, err
+}
+
+func (r *GeocodingResolver) cached(key string) (geocodingEntry, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.cache[key]
+	if !ok {
+		return geocodingEntry{}, false
+	}
+	if !r.clock.Now().Before(e.expiresAt) {
+		delete(r.cache, key)
+		return geocodingEntry{}, false
+	}
+	return e, true
+}
+
+// store caches e under key. When the cache is full, expired entries are
+// swept first and, if that frees nothing, the entry closest to expiry is
+// evicted. Stores only follow API lookups, which are rate-bounded by the
+// semaphore, so the linear scans stay cheap.
+func (r *GeocodingResolver) store(key string, e geocodingEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.cache[key]; !ok && len(r.cache) >= maxGeocodingEntries {
+		r.evictLocked()
+	}
+	r.cache[key] = e
+}
+
+// evictLocked makes room for one entry. r.mu must be held.
+func (r *GeocodingResolver) evictLocked() {
+	now := r.clock.Now()
+	for k, e := range r.cache {
+		if !now.Before(e.expiresAt) {
+			delete(r.cache, k)
+		}
+	}
+	if len(r.cache) < maxGeocodingEntries {
+		return
+	}
+
+	var (
+		oldest    string
+		oldestExp time.Time
+	)
+	for k, e := range r.cache {
+		if oldest == "" || e.expiresAt.Before(oldestExp) {
+			oldest, oldestExp = k, e.expiresAt
+		}
+	}
+	delete(r.cache, oldest)
+}
+
+func (r *GeocodingResolver) lookup(ctx context.Context, city string) (Location, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	q := url.Values{}
+	q.Set("name", city)
+	q.Set("count", "1")
+	q.Set("format", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		slog.Error("failed to create geocoding request",
+			"city", city,
+			"error", err,
+		)
+		return Location{}, ErrProviderUnavailable
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		slog.Warn("geocoding request failed",
+			"city", city,
+			"error", err,
+		)
+		return Location{}, requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		slog.Warn("geocoding returned non-200 status",
+			"city", city,
+			"status", resp.StatusCode,
+		)
+		return Location{}, ErrProviderUnavailable
+	}
+
+	var gr geocodingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gr); err != nil {
+		slog.Warn("failed to decode geocoding response",
+			"city", city,
+			"error", err,
+		)
+		return Location{}, requestError(ctx, err)
+	}
+
+	if len(gr.Results) == 0 {
+		return Location{}, ErrCityNotFound
+	}
+
+	res := gr.Results[0]
+	return Location{
+		Name:    res.Name,
+		Lat:     res.Latitude,
+		Lon:     res.Longitude,
+		Country: res.CountryCode,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package weather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+)
+
+func TestGeocodingCacheIsBounded(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewGeocodingResolver(nil, time.Second, 1, time.Hour, time.Minute, clk)
+
+	for i := range maxGeocodingEntries {
+		r.store("city-"+strconv.Itoa(i), geocodingEntry{expiresAt: clk.Now().Add(time.Hour + time.Duration(i))})
+	}
+	r.store("extra", geocodingEntry{expiresAt: clk.Now().Add(2 * time.Hour)})
+
+	if got := len(r.cache); got != maxGeocodingEntries {
+		t.Fatalf("cache size = %d, want %d", got, maxGeocodingEntries)
+	}
+	if _, ok := r.cache["city-0"]; ok {
+		t.Error("entry closest to expiry was not evicted")
+	}
+	if _, ok := r.cached("extra"); !ok {
+		t.Error("new entry missing")
+	}
+}
+
+func TestGeocodingCacheSweepsExpired(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	r := NewGeocodingResolver(nil, time.Second, 1, time.Hour, time.Minute, clk)
+
+	for i := range maxGeocodingEntries {
+		r.store("city-"+strconv.Itoa(i), geocodingEntry{expiresAt: clk.Now().Add(time.Minute)})
+	}
+	clk.Advance(2 * time.Minute)
+	r.store("fresh", geocodingEntry{expiresAt: clk.Now().Add(time.Hour)})
+
+	if got := len(r.cache); got != 1 {
+		t.Fatalf("cache size = %d, want 1 after sweeping expired entries", got)
+	}
+}
+
+func TestNormalizeCity(t *testing.T) {
+	tests := map[string]string{
+		"  London ":  "london",
+		"New   York": "new york",
+		"NEW\tYORK":  "new york",
+		"MÜNCHEN":    "münchen",
+		"Ωμέγας":     "ωμέγασ",
+		"ΩΜΈΓΑΣ":     "ωμέγασ",
+		"":           "",
+	}
+
+	for in, want := range tests {
+		if got := NormalizeCity(in); got != want {
+			t.Errorf("NormalizeCity(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGeocodingErrors(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("name") {
+		case "Slowtown":
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+
+	r := NewGeocodingResolver(nil, 50*time.Millisecond, 1, time.Hour, time.Minute, nil)
+	r.baseURL = srv.URL
+
+	if _, err := r.Resolve(context.Background(), "Slowtown"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("lookup past its timeout: err = %v, want ErrTimeout", err)
+	}
+	if _, err := r.Resolve(context.Background(), "Brokentown"); !errors.Is(err, ErrProviderUnavailable) {
+		t.Errorf("non-200 answer: err = %v, want ErrProviderUnavailable", err)
+	}
+
+	// A caller giving up while waiting for a lookup slot times out too.
+	r.sem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.Resolve(ctx, "Brokentown"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("waiting for a slot past the deadline: err = %v, want ErrTimeout", err)
+	}
+	<-r.sem
+}
@@ -0,0 +1,43 @@
+package weather
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultLanguage is the language of descriptions from providers that do
+// not localize them, or when no language is requested.
+const DefaultLanguage = "en"
+
+// languagePattern accepts language codes as providers take them,
+// e.g. "de", "pt_br" or "zh-cn".
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}([_-][a-z0-9]{2,4})?$`)
+
+// ParseLanguage normalizes a language code to lower case and checks its
+// format. It does not check that any provider supports the language.
+func ParseLanguage(s string) (string, error) {
+	lang := strings.ToLower(strings.TrimSpace(s))
+	if !languagePattern.MatchString(lang) {
+		return "", fmt.Errorf("invalid language %q, expected a code such as \"de\" or \"pt_br\"", s)
+	}
+	return lang, nil
+}
+
+type languageKey struct{}
+
+// WithLanguage returns a copy of ctx requesting descriptions in lang.
+// Providers that support localization pass it upstream and set Language
+// on their results; the others ignore it. An empty lang keeps the
+// Service default.
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// Language returns the description language requested by ctx, or "" for
+// the provider's default.
+func Language(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
@@ -0,0 +1,137 @@
+package weather
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyWindowSize is the number of recent calls kept per provider.
+	latencyWindowSize = 20
+
+	// minLatencySamples is the number of samples needed before a provider
+	// can be marked degraded.
+	minLatencySamples = 5
+
+	// degradedProbeInterval is how often a degraded provider is still
+	// called to check whether its latency recovered.
+	degradedProbeInterval = 30 * time.Second
+)
+
+// ProviderStatus describes a provider's capabilities, recent latency and
+// rate-limit budget.
+type ProviderStatus struct {
+	Name         string
+	Capabilities Capabilities
+	Samples      int
+	P95          time.Duration
+	Degraded     bool
+	Budget       *Budget // nil if the provider does not track one
+}
+
+// latencyTracker keeps a rolling latency window per provider and decides
+// which providers are degraded, i.e. over the latency budget.
+type latencyTracker struct {
+	budget time.Duration // 0 disables degraded mode
+
+	mu        sync.Mutex
+	windows   map[string][]time.Duration
+	degraded  map[string]bool
+	lastProbe map[string]time.Time
+}
+
+func newLatencyTracker(budget time.Duration) *latencyTracker {
+	return &latencyTracker{
+		budget:    budget,
+		windows:   make(map[string][]time.Duration),
+		degraded:  make(map[string]bool),
+		lastProbe: make(map[string]time.Time),
+	}
+}
+
+// record adds a call latency observed at now and updates the provider's
+// degraded state.
+func (t *latencyTracker) record(name string, d time.Duration, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.budget > 0 && t.degraded[name] && d <= t.budget {
+		// A probe came back within budget: start over with a clean window.
+		t.windows[name] = append(t.windows[name][:0], d)
+		t.degraded[name] = false
+		slog.Info("provider latency recovered, re-including",
+			"provider", name,
+			"latency", d.String(),
+		)
+		return
+	}
+
+	w := t.windows[name]
+	if len(w) == latencyWindowSize {
+		copy(w, w[1:])
+		w = w[:latencyWindowSize-1]
+	}
+	w = append(w, d)
+	t.windows[name] = w
+
+	if t.budget <= 0 || t.degraded[name] || len(w) < minLatencySamples {
+		return
+	}
+	if p95 := percentile(w, 0.95); p95 > t.budget {
+		t.degraded[name] = true
+		t.lastProbe[name] = now
+		slog.Warn("provider over latency budget, excluding",
+			"provider", name,
+			"p95", p95.String(),
+			"budget", t.budget.String(),
+		)
+	}
+}
+
+// allow reports whether a provider should be called now. Degraded
+// providers are let through once per degradedProbeInterval as a probe.
+func (t *latencyTracker) allow(name string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.degraded[name] {
+		return true
+	}
+	if now.Sub(t.lastProbe[name]) >= degradedProbeInterval {
+		t.lastProbe[name] = now
+		return true
+	}
+	return false
+}
+
+// status returns the latency status of the named providers.
+func (t *latencyTracker) status(names []string) []ProviderStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := make([]ProviderStatus, 0, len(names))
+	for _, name := range names {
+		w := t.windows[name]
+		res = append(res, ProviderStatus{
+			Name:     name,
+			Samples:  len(w),
+			P95:      percentile(w, 0.95),
+			Degraded: t.degraded[name],
+		})
+	}
+	return res
+}
+
+// percentile returns the p-th percentile (nearest rank) of samples.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(rank, 0), len(sorted)-1)]
+}
@@ -0,0 +1,69 @@
+package weather
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrResponseTooLarge is returned while reading an upstream response body
+// that exceeds the LimitTransport size limit.
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// LimitTransport caps the size of response bodies, so a broken or
+// malicious upstream cannot exhaust memory. Reading past MaxBytes fails
+// with ErrResponseTooLarge, which providers report as
+// ErrProviderUnavailable. A response announcing a larger Content-Length
+// fails right away.
+type LimitTransport struct {
+	MaxBytes int64             // 0 or less disables the limit
+	Base     http.RoundTripper // nil means http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *LimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || t.MaxBytes <= 0 {
+		return resp, err
+	}
+
+	if resp.ContentLength > t.MaxBytes {
+		resp.Body.Close()
+		return nil, ErrResponseTooLarge
+	}
+
+	resp.Body = &limitedBody{body: resp.Body, remaining: t.MaxBytes}
+	return resp, nil
+}
+
+// limitedBody reads at most remaining bytes from body and fails instead
+// of truncating silently, which could still decode as valid JSON.
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	// Read one byte past the limit to tell "exactly at" from "over".
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
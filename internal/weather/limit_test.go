@@ -0,0 +1,68 @@
+package weather_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/andrqxa/weather-aggregator/internal/weather/weathertest"
+)
+
+func TestLimitTransport(t *testing.T) {
+	const limit = 1 << 10
+
+	tests := []struct {
+		name    string
+		size    int
+		chunked bool // omit Content-Length
+		wantErr bool
+	}{
+		{"below limit", limit - 1, false, false},
+		{"at limit", limit, true, false},
+		{"announced over limit", limit + 1, false, true},
+		{"chunked over limit", 4 * limit, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				body := strings.Repeat("x", tt.size)
+				if tt.chunked {
+					// Flushing before writing the body forces chunked encoding.
+					w.(http.Flusher).Flush()
+				}
+				_, _ = io.WriteString(w, body)
+			}))
+			defer srv.Close()
+
+			client := &http.Client{Transport: &weather.LimitTransport{MaxBytes: limit}}
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+
+			if got := errors.Is(err, weather.ErrResponseTooLarge); got != tt.wantErr {
+				t.Errorf("err = %v, want ErrResponseTooLarge: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenMeteoOversizedResponse(t *testing.T) {
+	srv := weathertest.NewServer()
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: &weather.LimitTransport{MaxBytes: 256}}
+	p := weather.NewOpenMeteoProvider(client, weathertest.Resolver{},
+		weather.WithOpenMeteoBaseURL(srv.URL+weathertest.OpenMeteoPath))
+
+	if _, err := p.FetchForecast(context.Background(), "London", 2); !errors.Is(err, weather.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
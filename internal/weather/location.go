@@ -0,0 +1,103 @@
+package weather
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// maxLocationSpreadKm is how far apart two providers' resolved coordinates
+// may be while still describing the same place. Grid snapping and
+// different geocoders easily account for a few kilometres; two cities
+// sharing a name are usually much further apart.
+const maxLocationSpreadKm = 50.0
+
+// earthRadiusKm is the mean Earth radius.
+const earthRadiusKm = 6371.0
+
+// sameLocation keeps the results that describe the same place as the
+// majority. Results are clustered around the first result that is within
+// maxLocationSpreadKm; the largest cluster wins and ties go to the cluster
+// holding the earliest (highest-priority) result. Results without
+// coordinates cannot disagree and are always kept. The dropped results
+// are returned as exclusions.
+func sameLocation[T any](results []T, locate func(T) (Source, *Coordinates)) ([]T, []Exclusion) {
+	type cluster struct {
+		center  Coordinates
+		members []int
+	}
+
+	var clusters []cluster
+	for i, r := range results {
+		_, c := locate(r)
+		if c == nil {
+			continue
+		}
+		joined := false
+		for j := range clusters {
+			if distanceKm(clusters[j].center, *c) <= maxLocationSpreadKm {
+				clusters[j].members = append(clusters[j].members, i)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			clusters = append(clusters, cluster{center: *c, members: []int{i}})
+		}
+	}
+
+	if len(clusters) <= 1 {
+		return results, nil
+	}
+
+	best := 0
+	for j, cl := range clusters {
+		if len(cl.members) > len(clusters[best].members) {
+			best = j
+		}
+	}
+
+	keep := make(map[int]bool, len(clusters[best].members))
+	for _, i := range clusters[best].members {
+		keep[i] = true
+	}
+
+	center := clusters[best].center
+	res := make([]T, 0, len(results))
+	var (
+		dropped  []Source
+		excluded []Exclusion
+	)
+	for i, r := range results {
+		src, c := locate(r)
+		if c == nil || keep[i] {
+			res = append(res, r)
+			continue
+		}
+		dropped = append(dropped, src)
+		excluded = append(excluded, Exclusion{
+			Source: src,
+			Reason: ExclusionDifferentLocation,
+			Detail: fmt.Sprintf("resolved %.0f km from the other providers", distanceKm(center, *c)),
+		})
+	}
+
+	slog.Warn("providers resolved the city to different places, dropping minority",
+		"kept_lat", center.Lat,
+		"kept_lon", center.Lon,
+		"dropped", dropped,
+	)
+	return res, excluded
+}
+
+// distanceKm returns the great-circle distance between a and b.
+func distanceKm(a, b Coordinates) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(min(h, 1)))
+}
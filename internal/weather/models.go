@@ -1,6 +1,9 @@
 package weather
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // Source represents a weather data provider.
 type Source string
@@ -9,35 +12,156 @@ const (
 	SourceOpenWeather Source = "openweather"
 	SourceOpenMeteo   Source = "openmeteo"
 	SourceWeatherAPI  Source = "weatherapi"
+	SourceNWS         Source = "nws"
+
+	// SourceAggregated marks values combined from several sources;
+	// the contributing ones are listed in Sources.
+	SourceAggregated Source = "aggregated"
+
+	// SourceSynthetic marks a forecast synthesized from current weather
+	// because no forecast provider succeeded; see SyntheticForecast.
+	SourceSynthetic Source = "synthetic"
+)
+
+// DefaultSourcePriority is the order in which sources are preferred when
+// aggregation needs a deterministic tie-break.
+var DefaultSourcePriority = []Source{
+	SourceOpenMeteo,
+	SourceOpenWeather,
+	SourceWeatherAPI,
+	SourceNWS,
+}
+
+// ParseSources converts source names into Sources, returning the names
+// that do not match a known source separately.
+func ParseSources(names []string) (sources []Source, unknown []string) {
+	for _, n := range names {
+		src := Source(n)
+		if !slices.Contains(DefaultSourcePriority, src) {
+			unknown = append(unknown, n)
+			continue
+		}
+		sources = append(sources, src)
+	}
+	return sources, unknown
+}
+
+// sourceRank returns the position of src in priority; unknown sources
+// rank after all listed ones.
+func sourceRank(src Source, priority []Source) int {
+	for i, p := range priority {
+		if p == src {
+			return i
+		}
+	}
+	return len(priority)
+}
+
+// ConditionCode is a provider-independent weather condition suitable
+// for mapping to icons.
+type ConditionCode string
+
+const (
+	ConditionUnknown      ConditionCode = "unknown"
+	ConditionClear        ConditionCode = "clear"
+	ConditionPartlyCloudy ConditionCode = "partly_cloudy"
+	ConditionCloudy       ConditionCode = "cloudy"
+	ConditionFog          ConditionCode = "fog"
+	ConditionDrizzle      ConditionCode = "drizzle"
+	ConditionRain         ConditionCode = "rain"
+	ConditionSleet        ConditionCode = "sleet"
+	ConditionSnow         ConditionCode = "snow"
+	ConditionThunderstorm ConditionCode = "thunderstorm"
 )
 
 // CurrentWeather represents normalized current weather data.
 type CurrentWeather struct {
-	City        string    `json:"city"`
-	Temperature float64   `json:"temperature"` // Celsius
-	Humidity    int       `json:"humidity"`    // %
-	WindSpeed   float64   `json:"wind_speed"`  // m/s
-	Description string    `json:"description"`
-	Source      Source    `json:"source"`
-	ObservedAt  time.Time `json:"observed_at"`
+	City             string        `json:"city"`
+	Temperature      float64       `json:"temperature"`              // Celsius
+	FeelsLike        *float64      `json:"feels_like,omitempty"`     // Celsius, derived; see FeelsLike
+	Humidity         int           `json:"humidity"`                 // %
+	WindSpeed        float64       `json:"wind_speed"`               // m/s
+	WindGust         float64       `json:"wind_gust,omitempty"`      // m/s
+	WindDirection    *int          `json:"wind_direction,omitempty"` // degrees the wind comes from
+	Description      string        `json:"description"`
+	Language         string        `json:"language,omitempty"` // of Description
+	Condition        ConditionCode `json:"condition"`
+	RawConditionCode int           `json:"raw_condition_code,omitempty"` // provider-native code, e.g. WMO
+	Source           Source        `json:"source"`
+	Sources          []Source      `json:"sources"`
+	Aggregation      string        `json:"aggregation,omitempty"` // strategy applied, or "single"
+	Coordinates      *Coordinates  `json:"coordinates,omitempty"` // where the provider resolved the city
+	Quality          *Quality      `json:"quality,omitempty"`     // set when inputs were excluded
+	ObservedAt       time.Time     `json:"observed_at"`
 }
 
 // ForecastItem represents a single forecast point.
 type ForecastItem struct {
-	TimeStamp   time.Time `json:"timestamp"`
-	Temperature float64   `json:"temperature"` // Celsius
-	Humidity    int       `json:"humidity"`    // %
-	WindSpeed   float64   `json:"wind_speed"`  // m/s
-	Description string    `json:"description"`
-	Source      Source    `json:"source"`
+	TimeStamp        time.Time     `json:"timestamp"`
+	Temperature      float64       `json:"temperature"`              // Celsius
+	Humidity         int           `json:"humidity"`                 // %
+	WindSpeed        float64       `json:"wind_speed"`               // m/s
+	WindGust         float64       `json:"wind_gust,omitempty"`      // m/s
+	WindDirection    *int          `json:"wind_direction,omitempty"` // degrees the wind comes from
+	Description      string        `json:"description"`
+	Condition        ConditionCode `json:"condition"`
+	RawConditionCode int           `json:"raw_condition_code,omitempty"` // provider-native code, e.g. WMO
+	Source           Source        `json:"source"`
 }
 
 // Forecast represents normalized forecast for a city.
 type Forecast struct {
-	City      string         `json:"city"`
-	Items     []ForecastItem `json:"items"`
-	Days      int            `json:"days"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	City        string         `json:"city"`
+	Items       []ForecastItem `json:"items"`
+	Days        int            `json:"days"`        // requested
+	ActualDays  int            `json:"actual_days"` // distinct UTC days covered by Items
+	Source      Source         `json:"source"`
+	Sources     []Source       `json:"sources"`
+	Aggregation string         `json:"aggregation,omitempty"` // strategy applied, or "single"
+	Language    string         `json:"language,omitempty"`    // of item descriptions
+	Coordinates *Coordinates   `json:"coordinates,omitempty"` // where the provider resolved the city
+	Quality     *Quality       `json:"quality,omitempty"`     // set when inputs were excluded
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// Synthetic reports whether f was synthesized from current weather
+// rather than forecast by a provider.
+func (f Forecast) Synthetic() bool {
+	return f.Source == SourceSynthetic
+}
+
+// Coordinates is a point a provider resolved a queried city to.
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Reasons a provider result is excluded from an aggregate.
+const (
+	ExclusionOutOfRange        = "out_of_range"
+	ExclusionDifferentLocation = "different_location"
+	ExclusionEmpty             = "empty"
+)
+
+// Exclusion records a provider result left out of an aggregate.
+type Exclusion struct {
+	Source Source `json:"source"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Quality describes how an aggregate was built: which provider results
+// were excluded from it and why.
+type Quality struct {
+	Excluded []Exclusion `json:"excluded"`
+}
+
+// qualityOf returns the Quality for excluded, or nil if nothing was.
+func qualityOf(excluded []Exclusion) *Quality {
+	if len(excluded) == 0 {
+		return nil
+	}
+	return &Quality{Excluded: excluded}
 }
 
 // AggregatedWeather is what we will store and serve via API.
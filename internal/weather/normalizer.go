@@ -1 +1,151 @@
 package weather
+
+import "strings"
+
+// ConditionFromWMO maps a WMO weather interpretation code (as used by
+// OpenMeteo) to a normalized ConditionCode.
+func ConditionFromWMO(code int) ConditionCode {
+	switch code {
+	case 0, 1:
+		return ConditionClear
+	case 2:
+		return ConditionPartlyCloudy
+	case 3:
+		return ConditionCloudy
+	case 45, 48:
+		return ConditionFog
+	case 51, 53, 55, 56, 57:
+		return ConditionDrizzle
+	case 61, 63, 65, 80, 81, 82:
+		return ConditionRain
+	case 66, 67:
+		return ConditionSleet
+	case 71, 73, 75, 77, 85, 86:
+		return ConditionSnow
+	case 95, 96, 99:
+		return ConditionThunderstorm
+	default:
+		return ConditionUnknown
+	}
+}
+
+// ConditionFromOpenWeatherIcon maps an OpenWeatherMap icon code
+// (e.g. "10d", "04n") to a normalized ConditionCode.
+func ConditionFromOpenWeatherIcon(icon string) ConditionCode {
+	// Day and night variants share the same condition.
+	icon = strings.TrimRight(icon, "dn")
+
+	switch icon {
+	case "01":
+		return ConditionClear
+	case "02":
+		return ConditionPartlyCloudy
+	case "03", "04":
+		return ConditionCloudy
+	case "09", "10":
+		return ConditionRain
+	case "11":
+		return ConditionThunderstorm
+	case "13":
+		return ConditionSnow
+	case "50":
+		return ConditionFog
+	default:
+		return ConditionUnknown
+	}
+}
+
+// ConditionFromWeatherAPICode maps a WeatherAPI.com condition code
+// to a normalized ConditionCode.
+func ConditionFromWeatherAPICode(code int) ConditionCode {
+	switch code {
+	case 1000:
+		return ConditionClear
+	case 1003:
+		return ConditionPartlyCloudy
+	case 1006, 1009:
+		return ConditionCloudy
+	case 1030, 1135, 1147:
+		return ConditionFog
+	case 1072, 1150, 1153, 1168, 1171:
+		return ConditionDrizzle
+	case 1063, 1180, 1183, 1186, 1189, 1192, 1195, 1240, 1243, 1246:
+		return ConditionRain
+	case 1069, 1198, 1201, 1204, 1207, 1237, 1249, 1252, 1261, 1264:
+		return ConditionSleet
+	case 1066, 1114, 1117, 1210, 1213, 1216, 1219, 1222, 1225, 1255, 1258:
+		return ConditionSnow
+	case 1087, 1273, 1276, 1279, 1282:
+		return ConditionThunderstorm
+	default:
+		return ConditionUnknown
+	}
+}
+
+// ConditionFromText maps a free-text condition description
+// (e.g. "Chance Light Rain", "Mostly Sunny") to a normalized ConditionCode.
+func ConditionFromText(text string) ConditionCode {
+	t := strings.ToLower(text)
+
+	switch {
+	case t == "":
+		return ConditionUnknown
+	case strings.Contains(t, "thunder"):
+		return ConditionThunderstorm
+	case strings.Contains(t, "sleet"), strings.Contains(t, "freezing"), strings.Contains(t, "ice"):
+		return ConditionSleet
+	case strings.Contains(t, "snow"), strings.Contains(t, "flurr"), strings.Contains(t, "blizzard"):
+		return ConditionSnow
+	case strings.Contains(t, "drizzle"):
+		return ConditionDrizzle
+	case strings.Contains(t, "rain"), strings.Contains(t, "shower"):
+		return ConditionRain
+	case strings.Contains(t, "fog"), strings.Contains(t, "mist"), strings.Contains(t, "haze"):
+		return ConditionFog
+	case strings.Contains(t, "partly"), strings.Contains(t, "mostly sunny"),
+		strings.Contains(t, "mostly clear"), strings.Contains(t, "few clouds"),
+		strings.Contains(t, "scattered clouds"):
+		return ConditionPartlyCloudy
+	case strings.Contains(t, "cloud"), strings.Contains(t, "overcast"):
+		return ConditionCloudy
+	case strings.Contains(t, "sunny"), strings.Contains(t, "clear"), strings.Contains(t, "fair"):
+		return ConditionClear
+	default:
+		return ConditionUnknown
+	}
+}
+
+// conditionDescriptions are the canonical English descriptions of the
+// known condition codes.
+var conditionDescriptions = map[ConditionCode]string{
+	ConditionClear:        "Clear",
+	ConditionPartlyCloudy: "Partly cloudy",
+	ConditionCloudy:       "Cloudy",
+	ConditionFog:          "Fog",
+	ConditionDrizzle:      "Drizzle",
+	ConditionRain:         "Rain",
+	ConditionSleet:        "Sleet",
+	ConditionSnow:         "Snow",
+	ConditionThunderstorm: "Thunderstorm",
+}
+
+// Description returns the canonical English description of c, or "" for
+// ConditionUnknown and unrecognized codes.
+func (c ConditionCode) Description() string {
+	return conditionDescriptions[c]
+}
+
+// fahrenheitToCelsius converts °F to °C.
+func fahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// mphToMs converts miles per hour to metres per second.
+func mphToMs(v float64) float64 {
+	return v * 0.44704
+}
+
+// kmhToMs converts kilometres per hour to metres per second.
+func kmhToMs(v float64) float64 {
+	return v / 3.6
+}
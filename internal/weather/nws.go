@@ -0,0 +1,448 @@
+package weather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+)
+
+// NWSProvider implements Provider using the US National Weather Service API
+// (https://api.weather.gov). It requires no API key but does require a
+// User-Agent identifying the application. Only US locations are supported.
+//
+// Fetching is a two-step process: the coordinates are first mapped to a
+// forecast grid via /points/{lat},{lon}, then the grid's hourly forecast is
+// requested. The grid for a coordinate is stable, so it is cached.
+type NWSProvider struct {
+	client    *http.Client
+	resolver  CoordinateResolver
+	baseURL   string
+	userAgent string
+	strict    bool // see WithNWSStrict
+	quota     *quota
+	clock     clock.Clock
+
+	// points caches grid lookups by coordinate, at most maxNWSPoints of
+	// them; see storePoint.
+	mu     sync.Mutex
+	points map[string]nwsPoint
+}
+
+// maxNWSPoints caps the grid point cache. Keys are coordinates of
+// geocoded, user-supplied cities, so without a cap random queries would
+// grow it forever.
+const maxNWSPoints = 10000
+
+// nwsPoint holds the forecast endpoints discovered for a coordinate.
+type nwsPoint struct {
+	ForecastHourly string
+
+	usedAt time.Time // last lookup served from the cache
+}
+
+// NWSOption configures optional NWSProvider behavior.
+type NWSOption func(*NWSProvider)
+
+// WithNWSBaseURL overrides the API base URL, e.g. to point the provider
+// at a test server.
+func WithNWSBaseURL(u string) NWSOption {
+	return func(p *NWSProvider) {
+		p.baseURL = strings.TrimRight(u, "/")
+	}
+}
+
+// WithNWSStrict makes the provider return ErrProviderUnavailable for
+// responses it would otherwise map on a best-effort basis: an unparsable
+// period, a missing humidity value or a forecast text that maps to no
+// known condition.
+func WithNWSStrict(strict bool) NWSOption {
+	return func(p *NWSProvider) {
+		p.strict = strict
+	}
+}
+
+// WithNWSDailyBudget declares the number of API calls allowed per UTC
+// day, counting grid point lookups. NWS publishes no fixed quota, so the
+// default 0 means unknown; rate-limit headers and 429 responses are still
+// honored.
+func WithNWSDailyBudget(calls int) NWSOption {
+	return func(p *NWSProvider) {
+		p.quota = newQuota(calls)
+	}
+}
+
+// WithNWSClock sets the clock used for the daily budget window, Retry-After
+// dates and the grid point cache. The real clock is used by default.
+func WithNWSClock(clk clock.Clock) NWSOption {
+	return func(p *NWSProvider) {
+		p.clock = clock.OrReal(clk)
+	}
+}
+
+// NewNWSProvider creates a new NWSProvider. If client is nil,
+// http.DefaultClient is used; if resolver is nil, only the built-in known
+// cities are supported.
+func NewNWSProvider(client *http.Client, resolver CoordinateResolver, userAgent string, opts ...NWSOption) *NWSProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if resolver == nil {
+		resolver = StaticResolver{}
+	}
+
+	p := &NWSProvider{
+		client:    client,
+		resolver:  resolver,
+		baseURL:   "https://api.weather.gov",
+		userAgent: userAgent,
+		points:    make(map[string]nwsPoint),
+		quota:     newQuota(0),
+		clock:     clock.Real{},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Budget reports the calls made and left today, per the declared daily
+// budget or rate-limit headers.
+func (p *NWSProvider) Budget() Budget {
+	return p.quota.budget(p.clock.Now())
+}
+
+// Name returns provider identifier.
+func (p *NWSProvider) Name() string {
+	return string(SourceNWS)
+}
+
+// nwsRegion covers the US, its territories and, for locations without a
+// known country, a box around the contiguous US, Alaska and Hawaii.
+var nwsRegion = &Region{
+	Countries: []string{"US", "PR", "GU", "VI", "AS", "MP"},
+	Box:       &BoundingBox{MinLat: 18, MaxLat: 72, MinLon: -180, MaxLon: -65},
+}
+
+// Capabilities reports supported request kinds.
+// NWS serves up to 7 days of hourly forecast for US locations only.
+func (p *NWSProvider) Capabilities() Capabilities {
+	return Capabilities{Current: true, Forecast: true, MaxForecastDays: 7, Region: nwsRegion}
+}
+
+// ---- NWS DTO ----
+
+type nwsPointsResponse struct {
+	Properties struct {
+		ForecastHourly string `json:"forecastHourly"`
+	} `json:"properties"`
+}
+
+type nwsForecastResponse struct {
+	Properties struct {
+		Periods []nwsPeriod `json:"periods"`
+	} `json:"properties"`
+}
+
+type nwsPeriod struct {
+	StartTime        string  `json:"startTime"`
+	Temperature      float64 `json:"temperature"`
+	TemperatureUnit  string  `json:"temperatureUnit"` // "F" or "C"
+	WindSpeed        string  `json:"windSpeed"`       // e.g. "10 mph", "5 to 10 mph"
+	ShortForecast    string  `json:"shortForecast"`
+	RelativeHumidity struct {
+		Value *float64 `json:"value"`
+	} `json:"relativeHumidity"`
+}
+
+// FetchCurrent returns the current hour of the NWS hourly forecast.
+func (p *NWSProvider) FetchCurrent(ctx context.Context, city string) (CurrentWeather, error) {
+	loc, periods, err := p.hourlyPeriods(ctx, city)
+	if err != nil {
+		return CurrentWeather{}, err
+	}
+	if len(periods) == 0 {
+		slog.Warn("NWS returned no forecast periods", "city", city)
+		return CurrentWeather{}, ErrProviderUnavailable
+	}
+
+	it, err := periods[0].toItem()
+	if err != nil {
+		slog.Warn("failed to map NWS period",
+			"city", city,
+			"error", err,
+		)
+		return CurrentWeather{}, ErrProviderUnavailable
+	}
+	if err := p.checkPeriod(city, periods[0], it); err != nil {
+		return CurrentWeather{}, err
+	}
+
+	return CurrentWeather{
+		City:        city,
+		Temperature: it.Temperature,
+		Humidity:    it.Humidity,
+		WindSpeed:   it.WindSpeed,
+		Description: it.Description,
+		Condition:   it.Condition,
+		Source:      SourceNWS,
+		Coordinates: loc.Coordinates(),
+		ObservedAt:  it.TimeStamp,
+	}, nil
+}
+
+// FetchForecast returns the hourly NWS forecast for the given number of days.
+func (p *NWSProvider) FetchForecast(ctx context.Context, city string, days int) (Forecast, error) {
+	loc, periods, err := p.hourlyPeriods(ctx, city)
+	if err != nil {
+		return Forecast{}, err
+	}
+
+	limit := min(days*24, len(periods))
+	items := make([]ForecastItem, 0, limit)
+
+	var (
+		dropped int
+		sample  error
+	)
+
+	for _, period := range periods[:limit] {
+		it, err := period.toItem()
+		if err != nil {
+			if err := strictAnomaly(p.strict, SourceNWS, city, "unparsable period", "error", err); err != nil {
+				return Forecast{}, err
+			}
+			if dropped == 0 {
+				sample = err
+			}
+			dropped++
+			continue
+		}
+		if err := p.checkPeriod(city, period, it); err != nil {
+			return Forecast{}, err
+		}
+		items = append(items, it)
+	}
+
+	if dropped > 0 {
+		slog.Warn("dropped unparsable NWS forecast periods",
+			"city", city,
+			"days", days,
+			"dropped", dropped,
+			"kept", len(items),
+			"sample", sample,
+		)
+	}
+
+	return withActualDays(Forecast{
+		City:        city,
+		Days:        days,
+		Items:       items,
+		Source:      SourceNWS,
+		Coordinates: loc.Coordinates(),
+	}, SourceNWS), nil
+}
+
+// hourlyPeriods resolves the city, discovers its forecast grid and returns
+// the resolved location with the hourly forecast periods.
+func (p *NWSProvider) hourlyPeriods(ctx context.Context, city string) (Location, []nwsPeriod, error) {
+	loc, err := p.resolver.Resolve(ctx, city)
+	if err != nil {
+		return Location{}, nil, err
+	}
+
+	point, err := p.point(ctx, loc)
+	if err != nil {
+		return Location{}, nil, err
+	}
+
+	var fr nwsForecastResponse
+	if err := p.getJSON(ctx, point.ForecastHourly, &fr); err != nil {
+		return Location{}, nil, err
+	}
+
+	return loc, fr.Properties.Periods, nil
+}
+
+// point returns the cached forecast endpoints for a location, looking them
+// up via /points on first use.
+func (p *NWSProvider) point(ctx context.Context, loc Location) (nwsPoint, error) {
+	key := fmt.Sprintf("%.4f,%.4f", loc.Lat, loc.Lon)
+
+	p.mu.Lock()
+	pt, ok := p.points[key]
+	if ok {
+		pt.usedAt = p.clock.Now()
+		p.points[key] = pt
+	}
+	p.mu.Unlock()
+	if ok {
+		return pt, nil
+	}
+
+	var pr nwsPointsResponse
+	if err := p.getJSON(ctx, p.baseURL+"/points/"+key, &pr); err != nil {
+		return nwsPoint{}, err
+	}
+	if pr.Properties.ForecastHourly == "" {
+		return nwsPoint{}, ErrProviderUnavailable
+	}
+
+	pt = nwsPoint{ForecastHourly: pr.Properties.ForecastHourly}
+	p.storePoint(key, pt)
+	return pt, nil
+}
+
+// storePoint caches pt under key. Grid points do not expire, so when the
+// cache is full the least recently used entry is evicted. Stores only
+// follow /points lookups, so the linear scan stays cheap.
+func (p *NWSProvider) storePoint(key string, pt nwsPoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.points[key]; !ok && len(p.points) >= maxNWSPoints {
+		var (
+			oldest     string
+			oldestUsed time.Time
+		)
+		for k, e := range p.points {
+			if oldest == "" || e.usedAt.Before(oldestUsed) {
+				oldest, oldestUsed = k, e.usedAt
+			}
+		}
+		delete(p.points, oldest)
+	}
+	pt.usedAt = p.clock.Now()
+	p.points[key] = pt
+}
+
+// getJSON performs a GET request with the headers NWS requires and decodes
+// the JSON body into dst. A 404 means the location is outside NWS coverage.
+func (p *NWSProvider) getJSON(ctx context.Context, u string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		slog.Error("failed to create NWS request",
+			"url", u,
+			"error", err,
+		)
+		return ErrProviderUnavailable
+	}
+	req.Header.Set("User-Agent", p.userAgent)
+	req.Header.Set("Accept", "application/geo+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		slog.Warn("NWS request failed",
+			"url", u,
+			"error", err,
+		)
+		return requestError(ctx, err)
+	}
+	defer resp.Body.Close()
+	p.quota.record(resp, p.clock.Now())
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrCityNotFound
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		slog.Warn("NWS rate limited the request",
+			"url", u,
+			"retry_after", resp.Header.Get("Retry-After"),
+		)
+		return rateLimitError(resp, p.clock.Now())
+	}
+	if resp.StatusCode != http.StatusOK {
+		slog.Warn("NWS returned non-200 status",
+			"url", u,
+			"status", resp.StatusCode,
+		)
+		return ErrProviderUnavailable
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		slog.Warn("failed to decode NWS response",
+			"url", u,
+			"error", err,
+		)
+		return requestError(ctx, err)
+	}
+
+	return nil
+}
+
+// checkPeriod reports the best-effort parts of mapping np to it, which
+// fail the response in strict mode (see strictAnomaly).
+func (p *NWSProvider) checkPeriod(city string, np nwsPeriod, it ForecastItem) error {
+	if np.RelativeHumidity.Value == nil {
+		if err := strictAnomaly(p.strict, SourceNWS, city, "missing humidity", "start_time", np.StartTime); err != nil {
+			return err
+		}
+	}
+	if it.Condition == ConditionUnknown {
+		return strictAnomaly(p.strict, SourceNWS, city, "unknown condition", "short_forecast", np.ShortForecast)
+	}
+	return nil
+}
+
+// toItem maps an NWS period into a normalized ForecastItem.
+func (np nwsPeriod) toItem() (ForecastItem, error) {
+	t, err := time.Parse(time.RFC3339, np.StartTime)
+	if err != nil {
+		return ForecastItem{}, fmt.Errorf("parse start time %q: %w", np.StartTime, err)
+	}
+
+	units := nwsUnits
+	units.Temperature = parseTemperatureUnit(np.TemperatureUnit, nwsUnits.Temperature)
+
+	wind, err := parseNWSWindSpeed(np.WindSpeed)
+	if err != nil {
+		return ForecastItem{}, err
+	}
+
+	humidity := 0
+	if np.RelativeHumidity.Value != nil {
+		humidity = int(*np.RelativeHumidity.Value + 0.5)
+	}
+
+	return ForecastItem{
+		TimeStamp:   t.UTC(),
+		Temperature: units.temperature(np.Temperature),
+		Humidity:    humidity,
+		WindSpeed:   wind,
+		Description: np.ShortForecast,
+		Condition:   ConditionFromText(np.ShortForecast),
+		Source:      SourceNWS,
+	}, nil
+}
+
+// parseNWSWindSpeed parses values like "10 mph" or "5 to 10 mph" into m/s.
+// Ranges are averaged.
+func parseNWSWindSpeed(raw string) (float64, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return 0, nil
+	}
+
+	units := Units{WindSpeed: parseSpeedUnit(fields[len(fields)-1], nwsUnits.WindSpeed)}
+
+	var sum float64
+	var n int
+	for _, f := range fields {
+		var v float64
+		if _, err := fmt.Sscanf(f, "%g", &v); err == nil {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("parse wind speed %q", raw)
+	}
+
+	return units.windSpeed(sum / float64(n)), nil
+}
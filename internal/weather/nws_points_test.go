@@ -0,0 +1,30 @@
+package weather
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+)
+
+func TestNWSPointCacheIsBounded(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := NewNWSProvider(nil, nil, "weather-aggregator-test", WithNWSClock(clk))
+
+	for i := range maxNWSPoints {
+		p.storePoint("point-"+strconv.Itoa(i), nwsPoint{})
+		clk.Advance(time.Second)
+	}
+	p.storePoint("extra", nwsPoint{})
+
+	if got := len(p.points); got != maxNWSPoints {
+		t.Fatalf("cache size = %d, want %d", got, maxNWSPoints)
+	}
+	if _, ok := p.points["point-0"]; ok {
+		t.Error("least recently used point was not evicted")
+	}
+	if _, ok := p.points["extra"]; !ok {
+		t.Error("new point missing")
+	}
+}
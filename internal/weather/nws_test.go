@@ -0,0 +1,168 @@
+package weather_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/andrqxa/weather-aggregator/internal/weather/weathertest"
+)
+
+func newFixtureNWS(t *testing.T, opts ...weather.NWSOption) *weather.NWSProvider {
+	t.Helper()
+
+	srv := weathertest.NewServer()
+	t.Cleanup(srv.Close)
+
+	opts = append([]weather.NWSOption{weather.WithNWSBaseURL(srv.URL + weathertest.NWSPath)}, opts...)
+	return weather.NewNWSProvider(nil, weathertest.Resolver{}, "weather-aggregator-test", opts...)
+}
+
+func TestNWSCurrentFixture(t *testing.T) {
+	p := newFixtureNWS(t)
+
+	w, err := p.FetchCurrent(context.Background(), "New York")
+	if err != nil {
+		t.Fatalf("FetchCurrent: %v", err)
+	}
+
+	if want := 5.0; math.Abs(w.Temperature-want) > 1e-9 {
+		t.Errorf("temperature = %v, want %v (41 °F)", w.Temperature, want)
+	}
+	if w.Humidity != 70 {
+		t.Errorf("humidity = %d, want 70", w.Humidity)
+	}
+	if want := 4.4704; math.Abs(w.WindSpeed-want) > 1e-9 {
+		t.Errorf("wind speed = %v, want %v (10 mph)", w.WindSpeed, want)
+	}
+	if w.Description != "Partly Sunny" {
+		t.Errorf("description = %q", w.Description)
+	}
+	if want := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC); !w.ObservedAt.Equal(want) {
+		t.Errorf("observed at = %v, want %v", w.ObservedAt, want)
+	}
+	if w.Source != weather.SourceNWS {
+		t.Errorf("source = %s", w.Source)
+	}
+}
+
+func TestNWSForecastFixture(t *testing.T) {
+	p := newFixtureNWS(t)
+
+	fc, err := p.FetchForecast(context.Background(), "New York", 1)
+	if err != nil {
+		t.Fatalf("FetchForecast: %v", err)
+	}
+
+	if len(fc.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(fc.Items))
+	}
+	// "5 to 15 mph" is averaged to 10 mph.
+	if want := 4.4704; math.Abs(fc.Items[1].WindSpeed-want) > 1e-9 {
+		t.Errorf("wind speed = %v, want %v", fc.Items[1].WindSpeed, want)
+	}
+	if fc.Items[1].Condition != weather.ConditionRain {
+		t.Errorf("condition = %s, want rain", fc.Items[1].Condition)
+	}
+}
+
+func TestNWSBudgetFollowsClock(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC))
+	p := newFixtureNWS(t, weather.WithNWSDailyBudget(2), weather.WithNWSClock(clk))
+
+	// One grid point lookup and one forecast request.
+	if _, err := p.FetchCurrent(context.Background(), "New York"); err != nil {
+		t.Fatalf("FetchCurrent: %v", err)
+	}
+	b := p.Budget()
+	if !b.Exhausted() {
+		t.Errorf("budget = %+v, want exhausted", b)
+	}
+	if want := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC); !b.ResetAt.Equal(want) {
+		t.Errorf("reset at = %v, want %v", b.ResetAt, want)
+	}
+
+	clk.Advance(time.Hour)
+	if b := p.Budget(); b.Exhausted() || b.Used != 0 {
+		t.Errorf("budget after midnight = %+v, want a fresh day", b)
+	}
+}
+
+func TestNWSRetryAfterDateUsesClock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", now.Add(90*time.Second).Format(http.TimeFormat))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+
+	p := weather.NewNWSProvider(nil, weathertest.Resolver{}, "weather-aggregator-test",
+		weather.WithNWSBaseURL(srv.URL),
+		weather.WithNWSClock(clock.NewManual(now)),
+	)
+
+	_, err := p.FetchCurrent(context.Background(), "New York")
+	var rl *weather.RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("err = %v, want RateLimitError", err)
+	}
+	if rl.RetryAfter != 90*time.Second {
+		t.Errorf("retry after = %v, want 90s", rl.RetryAfter)
+	}
+	if b := p.Budget(); !b.Exhausted() {
+		t.Errorf("budget after 429 = %+v, want exhausted", b)
+	}
+}
+
+// serveNWSPeriods returns a provider whose upstream serves one grid point
+// with the given hourly periods.
+func serveNWSPeriods(t *testing.T, periods string) *weather.NWSProvider {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("GET /points/{coords}", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"properties": {"forecastHourly": %q}}`, srv.URL+"/hourly")
+	})
+	mux.HandleFunc("GET /hourly", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"properties": {"periods": %s}}`, periods)
+	})
+
+	return weather.NewNWSProvider(nil, weathertest.Resolver{}, "weather-aggregator-test", weather.WithNWSBaseURL(srv.URL))
+}
+
+func TestNWSForecastDropsBadTimestamps(t *testing.T) {
+	logs := captureLog(t)
+	p := serveNWSPeriods(t, `[
+		{"startTime": "2025-01-01T00:00:00Z", "temperature": 50, "temperatureUnit": "F", "windSpeed": "5 mph", "shortForecast": "Sunny", "relativeHumidity": {"value": 60}},
+		{"startTime": "yesterday", "temperature": 51, "temperatureUnit": "F", "windSpeed": "5 mph", "shortForecast": "Sunny", "relativeHumidity": {"value": 60}},
+		{"startTime": "2025-01-01T02:00:00Z", "temperature": 59, "temperatureUnit": "F", "windSpeed": "5 mph", "shortForecast": "Sunny", "relativeHumidity": {"value": 60}}
+	]`)
+
+	fc, err := p.FetchForecast(context.Background(), "New York", 1)
+	if err != nil {
+		t.Fatalf("FetchForecast: %v", err)
+	}
+
+	if len(fc.Items) != 2 {
+		t.Fatalf("got %d items, want 2 without the bad period", len(fc.Items))
+	}
+	if fc.Items[0].Temperature != 10 || fc.Items[1].Temperature != 15 {
+		t.Errorf("temperatures = %v, %v, want 10 and 15", fc.Items[0].Temperature, fc.Items[1].Temperature)
+	}
+	for _, want := range []string{"dropped=1", "kept=2", "yesterday"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log %q does not contain %s", logs, want)
+		}
+	}
+}
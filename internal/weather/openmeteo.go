@@ -4,30 +4,123 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
 )
 
-// / OpenMeteoProvider implements Provider using https://api.open-meteo.com.
-// It does not require an API key and works with a fixed set of city → coordinates
-// mappings that is sufficient for this test task.
+// OpenMeteoProvider implements Provider using https://api.open-meteo.com.
+// It does not require an API key; city names are resolved to coordinates
+// with a CoordinateResolver.
 type OpenMeteoProvider struct {
-	client *http.Client
+	client   *http.Client
+	resolver CoordinateResolver
+	baseURL  string
+
+	// maxDroppedRatio is the fraction of unparsable hourly points above
+	// which a forecast is rejected.
+	maxDroppedRatio float64
+
+	// strict rejects responses with any anomaly instead of mapping what
+	// can be mapped; see WithOpenMeteoStrict.
+	strict bool
+
+	quota *quota
+	clock clock.Clock
 }
 
-// NewOpenMeteoProvider creates a new OpenMeteoProvider with the given HTTP client.
-// If client is nil, http.DefaultClient is used.
-func NewOpenMeteoProvider(client *http.Client) *OpenMeteoProvider {
+// defaultMaxDroppedRatio is used unless overridden with
+// WithOpenMeteoMaxDroppedRatio.
+const defaultMaxDroppedRatio = 0.5
+
+// openMeteoTimeLayout is the ISO 8601 local time format Open-Meteo uses;
+// with timezone=UTC (or GMT by default) the values are UTC.
+const openMeteoTimeLayout = "2006-01-02T15:04"
+
+// OpenMeteoOption configures optional OpenMeteoProvider behavior.
+type OpenMeteoOption func(*OpenMeteoProvider)
+
+// WithOpenMeteoBaseURL overrides the API base URL, e.g. to point the
+// provider at a test server.
+func WithOpenMeteoBaseURL(u string) OpenMeteoOption {
+	return func(p *OpenMeteoProvider) {
+		p.baseURL = strings.TrimRight(u, "/")
+	}
+}
+
+// WithOpenMeteoMaxDroppedRatio sets the fraction (0..1) of hourly points
+// that may fail to parse before FetchForecast returns ErrProviderUnavailable.
+func WithOpenMeteoMaxDroppedRatio(r float64) OpenMeteoOption {
+	return func(p *OpenMeteoProvider) {
+		p.maxDroppedRatio = r
+	}
+}
+
+// WithOpenMeteoStrict makes the provider return ErrProviderUnavailable for
+// responses it would otherwise map on a best-effort basis: a missing or
+// unparsable observation time or timestamp, hourly series of different
+// lengths, a missing humidity value or an unknown weather code. Gusts and
+// wind direction stay optional.
+func WithOpenMeteoStrict(strict bool) OpenMeteoOption {
+	return func(p *OpenMeteoProvider) {
+		p.strict = strict
+	}
+}
+
+// WithOpenMeteoDailyBudget declares the number of API calls allowed per
+// UTC day (10,000 for the free tier). Once used up, the Service skips the
+// provider until the next day. 0 means unknown, and the provider is never
+// skipped.
+func WithOpenMeteoDailyBudget(calls int) OpenMeteoOption {
+	return func(p *OpenMeteoProvider) {
+		p.quota = newQuota(calls)
+	}
+}
+
+// WithOpenMeteoClock sets the clock used for the daily budget window,
+// Retry-After dates and the current-weather fallback hour. The real clock
+// is used by default.
+func WithOpenMeteoClock(clk clock.Clock) OpenMeteoOption {
+	return func(p *OpenMeteoProvider) {
+		p.clock = clock.OrReal(clk)
+	}
+}
+
+// NewOpenMeteoProvider creates a new OpenMeteoProvider with the given HTTP client
+// and coordinate resolver. If client is nil, http.DefaultClient is used;
+// if resolver is nil, only the built-in known cities are supported.
+func NewOpenMeteoProvider(client *http.Client, resolver CoordinateResolver, opts ...OpenMeteoOption) *OpenMeteoProvider {
 	if client == nil {
 		client = http.DefaultClient
 	}
+	if resolver == nil {
+		resolver = StaticResolver{}
+	}
 
-	return &OpenMeteoProvider{
-		client: client,
+	p := &OpenMeteoProvider{
+		client:          client,
+		resolver:        resolver,
+		baseURL:         "https://api.open-meteo.com/v1",
+		maxDroppedRatio: defaultMaxDroppedRatio,
+		quota:           newQuota(0),
+		clock:           clock.Real{},
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
+}
+
+// Budget reports the calls made and left today, per the declared daily
+// budget or rate-limit headers.
+func (p *OpenMeteoProvider) Budget() Budget {
+	return p.quota.budget(p.clock.Now())
 }
 
 // Name returns provider identifier.
@@ -35,69 +128,123 @@ func (p *OpenMeteoProvider) Name() string {
 	return string(SourceOpenMeteo)
 }
 
-// coordinates holds a small, hard-coded city → lat/lon map for the test task.
-type coordinates struct {
-	Lat float64
-	Lon float64
-}
-
-var openMeteoCityCoords = map[string]coordinates{
-	"london": {
-		Lat: 51.5074,
-		Lon: -0.1278,
-	},
-	"paris": {
-		Lat: 48.8566,
-		Lon: 2.3522,
-	},
-	"warsaw": {
-		Lat: 52.2297,
-		Lon: 21.0122,
-	},
+// Capabilities reports supported request kinds.
+// OpenMeteo serves up to 16 days of forecast.
+func (p *OpenMeteoProvider) Capabilities() Capabilities {
+	return Capabilities{Current: true, Forecast: true, MaxForecastDays: 16}
 }
 
 // ---- OpenMeteo DTO ----
 
+// openMeteoError is the body Open-Meteo sends instead of data, usually
+// with status 400. Both response types embed it, so an error body with
+// status 200 is not mistaken for an all-zero reading.
+type openMeteoError struct {
+	Error  bool   `json:"error"`
+	Reason string `json:"reason"`
+}
+
 type openMeteoCurrentResponse struct {
+	openMeteoError
+
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 
 	CurrentWeather struct {
-		Temperature float64 `json:"temperature"` // °C
-		Humidity    int     `json:"humidity"`    // %
-		WindSpeed   float64 `json:"windspeed"`   // km/h
-		WeatherCode int     `json:"weathercode"`
-		Time        string  `json:"time"` // ISO8601
+		Temperature   float64  `json:"temperature"`   // °C
+		WindSpeed     float64  `json:"windspeed"`     // km/h
+		WindDirection *float64 `json:"winddirection"` // °
+		WeatherCode   int      `json:"weathercode"`
+		Time          string   `json:"time"` // ISO8601
 	} `json:"current_weather"`
+
+	// current_weather has no humidity or gusts; they are taken from the
+	// hourly series.
+	Hourly struct {
+		Time      []string  `json:"time"`
+		Humidity  []int     `json:"relativehumidity_2m"` // %
+		WindGusts []float64 `json:"windgusts_10m"`       // km/h
+	} `json:"hourly"`
 }
 
 // For forecast take the hourly-data and fold them into the plain list.
 type openMeteoForecastResponse struct {
+	openMeteoError
+
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 
-	Hourly struct {
-		Time        []string  `json:"time"`
-		Temperature []float64 `json:"temperature_2m"`
-		Humidity    []int     `json:"humidity_2m"`
-		WindSpeed   []float64 `json:"windspeed_10m"`
-		WeatherCode []int     `json:"weathercode"`
-	} `json:"hourly"`
+	Hourly openMeteoHourly `json:"hourly"`
+}
+
+type openMeteoHourly struct {
+	Time          []string   `json:"time"`
+	Temperature   []float64  `json:"temperature_2m"`
+	Humidity      []int      `json:"relativehumidity_2m"`
+	WindSpeed     []float64  `json:"windspeed_10m"`
+	WindGusts     []float64  `json:"windgusts_10m"`
+	WindDirection []*float64 `json:"winddirection_10m"`
+	WeatherCode   []int      `json:"weathercode"`
+}
+
+// alignedLength returns the number of hourly points for which every
+// series used in mapping has a value.
+func (h openMeteoHourly) alignedLength() int {
+	return min(len(h.Time), len(h.Temperature), len(h.WindSpeed), len(h.WeatherCode))
+}
+
+// humidityAt returns the humidity of point i, or 0 if the series is short.
+func (h openMeteoHourly) humidityAt(i int) int {
+	if i < len(h.Humidity) {
+		return h.Humidity[i]
+	}
+	return 0
+}
+
+// windGustAt returns the gust of point i in m/s, or 0 if the series is
+// short. Gusts are optional, so a short series does not drop the point.
+func (h openMeteoHourly) windGustAt(i int) float64 {
+	if i < len(h.WindGusts) {
+		return openMeteoUnits.windSpeed(h.WindGusts[i])
+	}
+	return 0
+}
+
+// windDirectionAt returns the wind direction of point i, or nil if the
+// series is short or has no value there.
+func (h openMeteoHourly) windDirectionAt(i int) *int {
+	if i < len(h.WindDirection) {
+		return degrees(h.WindDirection[i])
+	}
+	return nil
+}
+
+// degrees rounds an optional direction to whole degrees.
+func degrees(v *float64) *int {
+	if v == nil {
+		return nil
+	}
+	d := int(math.Round(*v))
+	return &d
 }
 
 // FetchCurrent returns normalized current weather for a given city using OpenMeteo.
 func (p *OpenMeteoProvider) FetchCurrent(ctx context.Context, city string) (CurrentWeather, error) {
-	coords, ok := openMeteoCityCoords[normalizeCity(city)]
-	if !ok {
-		return CurrentWeather{}, ErrCityNotFound
+	coords, err := p.resolver.Resolve(ctx, city)
+	if err != nil {
+		return CurrentWeather{}, err
 	}
 
-	endpoint := "https://api.open-meteo.com/v1/forecast"
+	endpoint := p.baseURL + "/forecast"
 
 	q := url.Values{}
 	q.Set("latitude", fmt.Sprintf("%f", coords.Lat))
 	q.Set("longitude", fmt.Sprintf("%f", coords.Lon))
 	q.Set("current_weather", "true")
+	q.Set("hourly", "relativehumidity_2m,windgusts_10m")
+	q.Set("forecast_days", "1")
+	q.Set("windspeed_unit", "kmh")
+	q.Set("timezone", "UTC")
 
 	u := endpoint + "?" + q.Encode()
 
@@ -112,19 +259,27 @@ func (p *OpenMeteoProvider) FetchCurrent(ctx context.Context, city string) (Curr
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		// ctx cancellation / timeout will be here too
 		slog.Warn("OpenMeteo request failed",
 			"city", city,
 			"error", err,
 		)
-		return CurrentWeather{}, ErrProviderUnavailable
+		return CurrentWeather{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
+	p.quota.record(resp, p.clock.Now())
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		slog.Warn("OpenMeteo rate limited the request",
+			"city", city,
+			"retry_after", resp.Header.Get("Retry-After"),
+		)
+		return CurrentWeather{}, rateLimitError(resp, p.clock.Now())
+	}
 	if resp.StatusCode != http.StatusOK {
 		slog.Warn("OpenMeteo returned non-200 status",
 			"city", city,
 			"status", resp.StatusCode,
+			"reason", openMeteoErrorReason(resp.Body),
 		)
 		return CurrentWeather{}, ErrProviderUnavailable
 	}
@@ -135,24 +290,76 @@ func (p *OpenMeteoProvider) FetchCurrent(ctx context.Context, city string) (Curr
 			"city", city,
 			"error", err,
 		)
+		return CurrentWeather{}, requestError(ctx, err)
+	}
+	if omResp.Error {
+		slog.Warn("OpenMeteo returned an error body",
+			"city", city,
+			"reason", omResp.Reason,
+		)
 		return CurrentWeather{}, ErrProviderUnavailable
 	}
 
-	observedAt := time.Now().UTC()
+	// A missing time is left zero: together with zero values it marks a
+	// placeholder response (see LooksEmpty).
+	var observedAt time.Time
 	if omResp.CurrentWeather.Time != "" {
-		if t, err := time.Parse(time.RFC3339, omResp.CurrentWeather.Time); err == nil {
+		if t, err := parseOpenMeteoTime(omResp.CurrentWeather.Time); err == nil {
 			observedAt = t
 		}
 	}
+	if observedAt.IsZero() {
+		if err := strictAnomaly(p.strict, SourceOpenMeteo, city, "missing or invalid observation time",
+			"time", omResp.CurrentWeather.Time); err != nil {
+			return CurrentWeather{}, err
+		}
+	}
+
+	// Use the hour nearest to the observation, not the first (midnight) one.
+	var (
+		humidity int
+		gust     float64
+	)
+	target := observedAt
+	if target.IsZero() {
+		target = p.clock.Now().UTC()
+	}
+	i := nearestHourIndex(omResp.Hourly.Time, target)
+	if i >= 0 {
+		if i < len(omResp.Hourly.Humidity) {
+			humidity = omResp.Hourly.Humidity[i]
+		}
+		if i < len(omResp.Hourly.WindGusts) {
+			gust = openMeteoUnits.windSpeed(omResp.Hourly.WindGusts[i])
+		}
+	}
+	if i < 0 || i >= len(omResp.Hourly.Humidity) {
+		if err := strictAnomaly(p.strict, SourceOpenMeteo, city, "no humidity for the observation hour"); err != nil {
+			return CurrentWeather{}, err
+		}
+	}
+
+	condition := ConditionFromWMO(omResp.CurrentWeather.WeatherCode)
+	if condition == ConditionUnknown {
+		if err := strictAnomaly(p.strict, SourceOpenMeteo, city, "unknown weather code",
+			"weathercode", omResp.CurrentWeather.WeatherCode); err != nil {
+			return CurrentWeather{}, err
+		}
+	}
 
 	cw := CurrentWeather{
 		City:        city,
-		Temperature: omResp.CurrentWeather.Temperature,
-		Humidity:    omResp.CurrentWeather.Humidity,
-		WindSpeed:   omResp.CurrentWeather.WindSpeed,
+		Temperature: openMeteoUnits.temperature(omResp.CurrentWeather.Temperature),
+		Humidity:    humidity,
+		WindSpeed:   openMeteoUnits.windSpeed(omResp.CurrentWeather.WindSpeed),
+		WindGust:    gust,
 		//Description: omResp.CurrentWeather.WeatherCode,
-		Source:     SourceOpenMeteo,
-		ObservedAt: observedAt,
+		WindDirection:    degrees(omResp.CurrentWeather.WindDirection),
+		Condition:        condition,
+		RawConditionCode: omResp.CurrentWeather.WeatherCode,
+		Source:           SourceOpenMeteo,
+		Coordinates:      coords.Coordinates(),
+		ObservedAt:       observedAt,
 	}
 
 	return cw, nil
@@ -162,17 +369,18 @@ func (p *OpenMeteoProvider) FetchCurrent(ctx context.Context, city string) (Curr
 // using OpenMeteo hourly forecast. Implementation is intentionally minimal
 // but demonstrates real HTTP integration.
 func (p *OpenMeteoProvider) FetchForecast(ctx context.Context, city string, days int) (Forecast, error) {
-	coords, ok := openMeteoCityCoords[normalizeCity(city)]
-	if !ok {
-		return Forecast{}, ErrCityNotFound
+	coords, err := p.resolver.Resolve(ctx, city)
+	if err != nil {
+		return Forecast{}, err
 	}
 
-	endpoint := "https://api.open-meteo.com/v1/forecast"
+	endpoint := p.baseURL + "/forecast"
 
 	q := url.Values{}
 	q.Set("latitude", fmt.Sprintf("%f", coords.Lat))
 	q.Set("longitude", fmt.Sprintf("%f", coords.Lon))
-	q.Set("hourly", "temperature_2m,weathercode,windspeed_10m,relativehumidity_2m")
+	q.Set("hourly", "temperature_2m,weathercode,windspeed_10m,windgusts_10m,winddirection_10m,relativehumidity_2m")
+	q.Set("windspeed_unit", "kmh")
 	q.Set("forecast_days", fmt.Sprintf("%d", days))
 	q.Set("timezone", "UTC")
 
@@ -195,15 +403,25 @@ func (p *OpenMeteoProvider) FetchForecast(ctx context.Context, city string, days
 			"days", days,
 			"error", err,
 		)
-		return Forecast{}, ErrProviderUnavailable
+		return Forecast{}, requestError(ctx, err)
 	}
 	defer resp.Body.Close()
+	p.quota.record(resp, p.clock.Now())
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		slog.Warn("OpenMeteo rate limited the forecast request",
+			"city", city,
+			"days", days,
+			"retry_after", resp.Header.Get("Retry-After"),
+		)
+		return Forecast{}, rateLimitError(resp, p.clock.Now())
+	}
 	if resp.StatusCode != http.StatusOK {
 		slog.Warn("OpenMeteo forecast returned non-200 status",
 			"city", city,
 			"days", days,
 			"status", resp.StatusCode,
+			"reason", openMeteoErrorReason(resp.Body),
 		)
 		return Forecast{}, ErrProviderUnavailable
 	}
@@ -215,44 +433,157 @@ func (p *OpenMeteoProvider) FetchForecast(ctx context.Context, city string, days
 			"days", days,
 			"error", err,
 		)
+		return Forecast{}, requestError(ctx, err)
+	}
+	if omResp.Error {
+		slog.Warn("OpenMeteo forecast returned an error body",
+			"city", city,
+			"days", days,
+			"reason", omResp.Reason,
+		)
 		return Forecast{}, ErrProviderUnavailable
 	}
 
-	items := make([]ForecastItem, 0, len(omResp.Hourly.Time))
+	// Open-Meteo occasionally truncates one of the hourly series. Align on the
+	// shortest one instead of emitting zero-valued points for the tail.
+	n := omResp.Hourly.alignedLength()
+	if n < len(omResp.Hourly.Time) {
+		slog.Warn("OpenMeteo hourly series length mismatch, dropping misaligned tail",
+			"city", city,
+			"days", days,
+			"time", len(omResp.Hourly.Time),
+			"temperature", len(omResp.Hourly.Temperature),
+			"windspeed", len(omResp.Hourly.WindSpeed),
+			"weathercode", len(omResp.Hourly.WeatherCode),
+			"aligned", n,
+		)
+	}
+	if n < len(omResp.Hourly.Time) || len(omResp.Hourly.Humidity) < len(omResp.Hourly.Time) {
+		if err := strictAnomaly(p.strict, SourceOpenMeteo, city, "hourly series length mismatch",
+			"time", len(omResp.Hourly.Time),
+			"humidity", len(omResp.Hourly.Humidity),
+			"aligned", n,
+		); err != nil {
+			return Forecast{}, err
+		}
+	}
 
-	for i := range omResp.Hourly.Time {
+	items := make([]ForecastItem, 0, n)
+
+	var (
+		dropped int
+		sample  string
+	)
+
+	for i := 0; i < n; i++ {
 		tStr := omResp.Hourly.Time[i]
-		t, err := time.Parse(time.RFC3339, tStr)
+		t, err := parseOpenMeteoTime(tStr)
 		if err != nil {
+			if dropped == 0 {
+				sample = tStr
+			}
+			dropped++
 			continue
 		}
 
+		condition := ConditionFromWMO(omResp.Hourly.WeatherCode[i])
+		if condition == ConditionUnknown {
+			if err := strictAnomaly(p.strict, SourceOpenMeteo, city, "unknown weather code",
+				"time", tStr,
+				"weathercode", omResp.Hourly.WeatherCode[i],
+			); err != nil {
+				return Forecast{}, err
+			}
+		}
+
 		item := ForecastItem{
-			TimeStamp:   t,
-			Temperature: safeIndexFloat(omResp.Hourly.Temperature, i),
-			//WindSpeed:   safeIndexFloat(omResp.Hourly.WindSpeed, i),
-			Source: SourceOpenMeteo,
+			TimeStamp:        t,
+			Temperature:      openMeteoUnits.temperature(omResp.Hourly.Temperature[i]),
+			Humidity:         omResp.Hourly.humidityAt(i),
+			WindSpeed:        openMeteoUnits.windSpeed(omResp.Hourly.WindSpeed[i]),
+			WindGust:         omResp.Hourly.windGustAt(i),
+			WindDirection:    omResp.Hourly.windDirectionAt(i),
+			Condition:        condition,
+			RawConditionCode: omResp.Hourly.WeatherCode[i],
+			Source:           SourceOpenMeteo,
 		}
 
 		items = append(items, item)
 	}
 
+	if dropped > 0 {
+		slog.Warn("dropped OpenMeteo forecast items with unparsable timestamps",
+			"city", city,
+			"days", days,
+			"dropped", dropped,
+			"kept", len(items),
+			"sample", sample,
+		)
+		if float64(dropped) > p.maxDroppedRatio*float64(n) {
+			return Forecast{}, ErrProviderUnavailable
+		}
+		if err := strictAnomaly(p.strict, SourceOpenMeteo, city, "unparsable timestamps",
+			"dropped", dropped,
+		); err != nil {
+			return Forecast{}, err
+		}
+	}
+
 	fc := Forecast{
-		City:  city,
-		Days:  days,
-		Items: items,
+		City:        city,
+		Days:        days,
+		Items:       items,
+		Source:      SourceOpenMeteo,
+		Coordinates: coords.Coordinates(),
 	}
 
-	return fc, nil
+	return withActualDays(fc, SourceOpenMeteo), nil
 }
 
-func safeIndexFloat(xs []float64, i int) float64 {
-	if i < 0 || i >= len(xs) {
-		return 0
+// maxErrorBodySize bounds how much of an error response is read.
+const maxErrorBodySize = 4 << 10
+
+// openMeteoErrorReason returns the reason from an Open-Meteo error body,
+// or "" if body is not one. Open-Meteo resolves nothing by name, so its
+// errors concern our request (e.g. coordinates out of range) and never
+// mean the city is unknown.
+func openMeteoErrorReason(body io.Reader) string {
+	var e openMeteoError
+	if err := json.NewDecoder(io.LimitReader(body, maxErrorBodySize)).Decode(&e); err != nil || !e.Error {
+		return ""
+	}
+	return e.Reason
+}
+
+// nearestHourIndex returns the index of the timestamp closest to target,
+// or -1 if none can be parsed.
+func nearestHourIndex(times []string, target time.Time) int {
+	best := -1
+	var bestDiff time.Duration
+
+	for i, ts := range times {
+		t, err := parseOpenMeteoTime(ts)
+		if err != nil {
+			continue
+		}
+		diff := t.Sub(target).Abs()
+		if best < 0 || diff < bestDiff {
+			best, bestDiff = i, diff
+		}
 	}
-	return xs[i]
+
+	return best
 }
 
-func normalizeCity(city string) string {
-	return strings.ToLower(strings.TrimSpace(city))
+// parseOpenMeteoTime parses an Open-Meteo timestamp as UTC. RFC 3339
+// values with an explicit offset are accepted as well.
+func parseOpenMeteoTime(s string) (time.Time, error) {
+	if t, err := time.Parse(openMeteoTimeLayout, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
 }
@@ -0,0 +1,338 @@
+package weather_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+	"github.com/andrqxa/weather-aggregator/internal/weather/weathertest"
+)
+
+// serveOpenMeteo returns a provider whose upstream answers every request
+// with status and body.
+func serveOpenMeteo(t *testing.T, status int, body string, opts ...weather.OpenMeteoOption) *weather.OpenMeteoProvider {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	opts = append([]weather.OpenMeteoOption{weather.WithOpenMeteoBaseURL(srv.URL)}, opts...)
+	return weather.NewOpenMeteoProvider(nil, weathertest.Resolver{}, opts...)
+}
+
+// mismatchedHourly has a truncated temperature and humidity series.
+const mismatchedHourly = `{
+  "hourly": {
+    "time": ["2025-01-01T00:00", "2025-01-01T01:00", "2025-01-01T02:00", "2025-01-01T03:00"],
+    "temperature_2m": [1.5, 2.5, 3.5],
+    "windspeed_10m": [10, 10, 10, 10],
+    "weathercode": [0, 1, 2, 3],
+    "relativehumidity_2m": [70, 71]
+  }
+}`
+
+func TestOpenMeteoForecastMismatchedSeries(t *testing.T) {
+	p := serveOpenMeteo(t, http.StatusOK, mismatchedHourly)
+
+	fc, err := p.FetchForecast(context.Background(), "London", 1)
+	if err != nil {
+		t.Fatalf("FetchForecast: %v", err)
+	}
+
+	if len(fc.Items) != 3 {
+		t.Fatalf("got %d items, want 3 aligned on the shortest series", len(fc.Items))
+	}
+	for i, want := range []float64{1.5, 2.5, 3.5} {
+		if fc.Items[i].Temperature != want {
+			t.Errorf("item %d temperature = %v, want %v", i, fc.Items[i].Temperature, want)
+		}
+	}
+	if got := fc.Items[2].Humidity; got != 0 {
+		t.Errorf("humidity past the short series = %d, want 0", got)
+	}
+}
+
+func TestOpenMeteoRateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+	p := weather.NewOpenMeteoProvider(nil, weathertest.Resolver{}, weather.WithOpenMeteoBaseURL(srv.URL))
+
+	_, err := p.FetchCurrent(context.Background(), "London")
+
+	var rl *weather.RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("err = %v, want a *RateLimitError", err)
+	}
+	if rl.RetryAfter != 2*time.Minute {
+		t.Errorf("RetryAfter = %v, want 2m", rl.RetryAfter)
+	}
+	if !errors.Is(err, weather.ErrProviderUnavailable) {
+		t.Error("rate limit error does not match ErrProviderUnavailable")
+	}
+}
+
+// The current fixture observes at 12:00 with an hourly series starting at
+// 10:00, so the observation hour is in the middle of the array.
+func TestOpenMeteoCurrentUsesObservationHour(t *testing.T) {
+	p := newFixtureOpenMeteo(t)
+
+	w, err := p.FetchCurrent(context.Background(), "London")
+	if err != nil {
+		t.Fatalf("FetchCurrent: %v", err)
+	}
+
+	if w.Humidity != 87 {
+		t.Errorf("humidity = %d, want 87 from the 12:00 point", w.Humidity)
+	}
+	if want := 33.8 / 3.6; math.Abs(w.WindGust-want) > 1e-9 {
+		t.Errorf("wind gust = %v, want %v from the 12:00 point", w.WindGust, want)
+	}
+}
+
+func newFixtureOpenMeteo(t *testing.T, opts ...weather.OpenMeteoOption) *weather.OpenMeteoProvider {
+	t.Helper()
+
+	srv := weathertest.NewServer()
+	t.Cleanup(srv.Close)
+
+	opts = append([]weather.OpenMeteoOption{weather.WithOpenMeteoBaseURL(srv.URL + weathertest.OpenMeteoPath)}, opts...)
+	return weather.NewOpenMeteoProvider(nil, weathertest.Resolver{}, opts...)
+}
+
+func TestOpenMeteoCurrentFixture(t *testing.T) {
+	p := newFixtureOpenMeteo(t)
+
+	w, err := p.FetchCurrent(context.Background(), "London")
+	if err != nil {
+		t.Fatalf("FetchCurrent: %v", err)
+	}
+
+	if w.Temperature != 8.4 {
+		t.Errorf("temperature = %v, want 8.4", w.Temperature)
+	}
+	if math.Abs(w.WindSpeed-5) > 1e-9 {
+		t.Errorf("wind speed = %v, want 5 m/s", w.WindSpeed)
+	}
+	if w.WindDirection == nil || *w.WindDirection != 240 {
+		t.Errorf("wind direction = %v, want 240", w.WindDirection)
+	}
+	if w.Condition != weather.ConditionRain || w.RawConditionCode != 61 {
+		t.Errorf("condition = %s (%d), want rain (61)", w.Condition, w.RawConditionCode)
+	}
+	if want := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC); !w.ObservedAt.Equal(want) {
+		t.Errorf("observed at = %v, want %v", w.ObservedAt, want)
+	}
+	if w.Source != weather.SourceOpenMeteo {
+		t.Errorf("source = %s", w.Source)
+	}
+}
+
+func TestOpenMeteoForecastFixture(t *testing.T) {
+	p := newFixtureOpenMeteo(t)
+
+	fc, err := p.FetchForecast(context.Background(), "London", 2)
+	if err != nil {
+		t.Fatalf("FetchForecast: %v", err)
+	}
+
+	if len(fc.Items) != 8 || fc.ActualDays != 2 {
+		t.Fatalf("got %d items over %d days, want 8 over 2", len(fc.Items), fc.ActualDays)
+	}
+	first, last := fc.Items[0], fc.Items[7]
+	if first.Temperature != 5.1 || first.Condition != weather.ConditionCloudy || first.Humidity != 88 {
+		t.Errorf("first item = %+v", first)
+	}
+	if math.Abs(last.WindSpeed-6) > 1e-9 || last.Condition != weather.ConditionSnow {
+		t.Errorf("last item = %+v", last)
+	}
+
+	// windspeed_10m and windgusts_10m come in km/h: 10.8 and 21.6.
+	if math.Abs(first.WindSpeed-3) > 1e-9 || math.Abs(first.WindGust-6) > 1e-9 {
+		t.Errorf("first item wind = %v m/s gusting %v m/s, want 3 and 6", first.WindSpeed, first.WindGust)
+	}
+	if first.WindDirection == nil || *first.WindDirection != 230 {
+		t.Errorf("first item wind direction = %v, want 230", first.WindDirection)
+	}
+}
+
+func TestOpenMeteoErrorFixture(t *testing.T) {
+	p := newFixtureOpenMeteo(t)
+
+	if _, err := p.FetchCurrent(context.Background(), weathertest.InvalidCity); !errors.Is(err, weather.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+	if _, err := p.FetchCurrent(context.Background(), "Atlantis"); !errors.Is(err, weather.ErrCityNotFound) {
+		t.Errorf("err = %v, want ErrCityNotFound", err)
+	}
+}
+
+func TestOpenMeteoErrorBodies(t *testing.T) {
+	body := string(weathertest.Fixture("openmeteo_error"))
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusOK} {
+		p := serveOpenMeteo(t, status, body)
+
+		_, err := p.FetchCurrent(context.Background(), "London")
+		if !errors.Is(err, weather.ErrProviderUnavailable) || errors.Is(err, weather.ErrCityNotFound) {
+			t.Errorf("status %d: current err = %v, want ErrProviderUnavailable", status, err)
+		}
+		_, err = p.FetchForecast(context.Background(), "London", 1)
+		if !errors.Is(err, weather.ErrProviderUnavailable) || errors.Is(err, weather.ErrCityNotFound) {
+			t.Errorf("status %d: forecast err = %v, want ErrProviderUnavailable", status, err)
+		}
+	}
+}
+
+// anomalousCurrent has an unknown weather code and no humidity series.
+const anomalousCurrent = `{
+  "current_weather": {"time": "2025-01-01T12:00", "temperature": 8.4, "windspeed": 18, "weathercode": 1234},
+  "hourly": {"time": ["2025-01-01T12:00"]}
+}`
+
+func TestOpenMeteoStrictMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		fetch func(*weather.OpenMeteoProvider) error
+	}{
+		{"current", anomalousCurrent, func(p *weather.OpenMeteoProvider) error {
+			_, err := p.FetchCurrent(context.Background(), "London")
+			return err
+		}},
+		{"forecast", mismatchedHourly, func(p *weather.OpenMeteoProvider) error {
+			_, err := p.FetchForecast(context.Background(), "London", 1)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/lenient", func(t *testing.T) {
+			p := serveOpenMeteo(t, http.StatusOK, tt.body, weather.WithOpenMeteoStrict(false))
+			if err := tt.fetch(p); err != nil {
+				t.Errorf("err = %v, want the anomalies mapped on a best-effort basis", err)
+			}
+		})
+		t.Run(tt.name+"/strict", func(t *testing.T) {
+			p := serveOpenMeteo(t, http.StatusOK, tt.body, weather.WithOpenMeteoStrict(true))
+			if err := tt.fetch(p); !errors.Is(err, weather.ErrProviderUnavailable) {
+				t.Errorf("err = %v, want ErrProviderUnavailable", err)
+			}
+		})
+	}
+
+	// Well-formed responses pass in strict mode too.
+	p := newFixtureOpenMeteo(t, weather.WithOpenMeteoStrict(true))
+	if _, err := p.FetchCurrent(context.Background(), "London"); err != nil {
+		t.Errorf("strict current from fixture: %v", err)
+	}
+	if _, err := p.FetchForecast(context.Background(), "London", 2); err != nil {
+		t.Errorf("strict forecast from fixture: %v", err)
+	}
+}
+
+func TestOpenMeteoBudgetFollowsClock(t *testing.T) {
+	clk := clock.NewManual(time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC))
+	p := newFixtureOpenMeteo(t, weather.WithOpenMeteoDailyBudget(1), weather.WithOpenMeteoClock(clk))
+
+	if _, err := p.FetchCurrent(context.Background(), "London"); err != nil {
+		t.Fatalf("FetchCurrent: %v", err)
+	}
+	b := p.Budget()
+	if !b.Exhausted() {
+		t.Errorf("budget = %+v, want exhausted", b)
+	}
+	if want := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC); !b.ResetAt.Equal(want) {
+		t.Errorf("reset at = %v, want %v", b.ResetAt, want)
+	}
+
+	clk.Advance(time.Hour)
+	if b := p.Budget(); b.Exhausted() || b.Used != 0 {
+		t.Errorf("budget after midnight = %+v, want a fresh day", b)
+	}
+}
+
+func TestOpenMeteoRetryAfterDateUsesClock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Retry-After", now.Add(90*time.Second).Format(http.TimeFormat))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+
+	p := weather.NewOpenMeteoProvider(nil, weathertest.Resolver{},
+		weather.WithOpenMeteoBaseURL(srv.URL),
+		weather.WithOpenMeteoClock(clock.NewManual(now)),
+	)
+
+	_, err := p.FetchCurrent(context.Background(), "London")
+	var rl *weather.RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("err = %v, want RateLimitError", err)
+	}
+	if rl.RetryAfter != 90*time.Second {
+		t.Errorf("retry after = %v, want 90s", rl.RetryAfter)
+	}
+}
+
+// captureLog routes the default logger into the returned buffer for the
+// rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// badTimestamp has one unparsable timestamp among four hourly points.
+const badTimestamp = `{
+  "hourly": {
+    "time": ["2025-01-01T00:00", "not-a-time", "2025-01-01T02:00", "2025-01-01T03:00"],
+    "temperature_2m": [1, 2, 3, 4],
+    "windspeed_10m": [10, 10, 10, 10],
+    "weathercode": [0, 1, 2, 3],
+    "relativehumidity_2m": [70, 71, 72, 73]
+  }
+}`
+
+func TestOpenMeteoForecastDropsBadTimestamps(t *testing.T) {
+	logs := captureLog(t)
+	p := serveOpenMeteo(t, http.StatusOK, badTimestamp)
+
+	fc, err := p.FetchForecast(context.Background(), "London", 1)
+	if err != nil {
+		t.Fatalf("FetchForecast: %v", err)
+	}
+
+	var temps []float64
+	for _, it := range fc.Items {
+		temps = append(temps, it.Temperature)
+	}
+	if !slices.Equal(temps, []float64{1, 3, 4}) {
+		t.Errorf("temperatures = %v, want [1 3 4] without the bad point", temps)
+	}
+	for _, want := range []string{"dropped=1", "kept=3", "sample=not-a-time"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log %q does not contain %s", logs, want)
+		}
+	}
+}
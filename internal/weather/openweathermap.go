@@ -24,14 +24,21 @@ func (p *OpenWeatherMapProvider) Name() string {
 	return string(SourceOpenWeather)
 }
 
+// Capabilities reports supported request kinds.
+// The free OpenWeatherMap plan serves up to 5 days of forecast.
+func (p *OpenWeatherMapProvider) Capabilities() Capabilities {
+	return Capabilities{Current: true, Forecast: true, MaxForecastDays: 5}
+}
+
 // FetchCurrent returns stubbed error for now.
-// Real implementation will call external API.
+// Real implementation will call external API, passing Language(ctx) as
+// the `lang` parameter and setting Language on the result.
 func (p *OpenWeatherMapProvider) FetchCurrent(ctx context.Context, city string) (CurrentWeather, error) {
 	return CurrentWeather{}, ErrProviderUnavailable
 }
 
 // FetchForecast returns stubbed error for now.
-// Real implementation will call external API.
+// Real implementation will call external API, localized like FetchCurrent.
 func (p *OpenWeatherMapProvider) FetchForecast(ctx context.Context, city string, days int) (Forecast, error) {
 	return Forecast{}, ErrProviderUnavailable
 }
@@ -0,0 +1,35 @@
+package weather
+
+import "context"
+
+// ProbeResult is the outcome of probing one provider; Err is nil if it
+// answered.
+type ProbeResult struct {
+	Provider string
+	Err      error
+}
+
+// Probe asks every provider serving current weather for city, in
+// parallel, and returns one result per provider in declared order. It
+// checks reachability, so unlike GetCurrentWeather it bypasses
+// coalescing, region and latency filtering, and aggregation.
+func (s *Service) Probe(ctx context.Context, city string) []ProbeResult {
+	providers := s.eligible(func(c Capabilities) bool { return c.Current })
+
+	resultsCh := fanOut(ctx, providers, 0, s.sequential, func(p Provider) (struct{}, error) {
+		_, err := p.FetchCurrent(ctx, city)
+		return struct{}{}, err
+	})
+
+	byProvider := make(map[Provider]error, len(providers))
+	for range providers {
+		r := <-resultsCh
+		byProvider[r.provider] = r.err
+	}
+
+	res := make([]ProbeResult, 0, len(providers))
+	for _, p := range providers {
+		res = append(res, ProbeResult{Provider: p.Name(), Err: byProvider[p]})
+	}
+	return res
+}
@@ -3,6 +3,11 @@ package weather
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Provider describes a weather data provider.
@@ -21,6 +26,76 @@ type Provider interface {
 	FetchForecast(ctx context.Context, city string, days int) (Forecast, error)
 }
 
+// Capabilities describes which requests a provider can serve.
+type Capabilities struct {
+	Current  bool
+	Forecast bool
+	// MaxForecastDays is the longest forecast horizon supported; 0 means unlimited.
+	MaxForecastDays int
+	// Region limits the locations the provider is called for; nil means worldwide.
+	Region *Region
+}
+
+// CapabilityReporter is implemented by providers that cannot serve every
+// kind of request. Providers not implementing it are assumed to support
+// current weather and forecasts of any length.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities declared by p.
+func CapabilitiesOf(p Provider) Capabilities {
+	if cr, ok := p.(CapabilityReporter); ok {
+		return cr.Capabilities()
+	}
+	return Capabilities{Current: true, Forecast: true}
+}
+
+// forecastDays clamps the requested days to the provider's horizon.
+func (c Capabilities) forecastDays(days int) int {
+	if c.MaxForecastDays > 0 {
+		return min(days, c.MaxForecastDays)
+	}
+	return days
+}
+
+// EmptyChecker is implemented by providers that need their own test for
+// placeholder responses (e.g. a 200 with an all-zero body during
+// maintenance). Other providers use LooksEmpty.
+type EmptyChecker interface {
+	LooksEmpty(w CurrentWeather) bool
+}
+
+// LooksEmpty reports whether w looks like a placeholder rather than a
+// reading: temperature, humidity and wind all zero and no observation time.
+// Any one of them being zero is plausible; all of them together is not.
+func LooksEmpty(w CurrentWeather) bool {
+	return w.Temperature == 0 && w.Humidity == 0 && w.WindSpeed == 0 && w.ObservedAt.IsZero()
+}
+
+// looksEmptyFor applies p's own empty check if it has one, else LooksEmpty.
+func looksEmptyFor(p Provider, w CurrentWeather) bool {
+	if ec, ok := p.(EmptyChecker); ok {
+		return ec.LooksEmpty(w)
+	}
+	return LooksEmpty(w)
+}
+
+// strictAnomaly handles an anomaly in a provider response that lenient
+// parsing works around with best-effort values, e.g. a missing field. In
+// strict mode the response is rejected: the anomaly is logged and
+// ErrProviderUnavailable returned. Otherwise it returns nil and the caller
+// carries on.
+func strictAnomaly(strict bool, src Source, city, anomaly string, args ...any) error {
+	if !strict {
+		return nil
+	}
+	slog.Warn("rejecting provider response in strict parsing mode",
+		append([]any{"provider", src, "city", city, "anomaly", anomaly}, args...)...,
+	)
+	return ErrProviderUnavailable
+}
+
 var (
 	// ErrCityNotFound is returned when provider does not know the requested city.
 	ErrCityNotFound = errors.New("city not found")
@@ -28,4 +103,58 @@ var (
 	// ErrProviderUnavailable is returned when provider cannot serve the request
 	// due to temporary issues (network, rate limiting, etc.).
 	ErrProviderUnavailable = errors.New("provider unavailable")
+
+	// ErrTimeout is returned when the request context expired before the
+	// provider answered, i.e. we gave up rather than the provider failing.
+	ErrTimeout = errors.New("provider request timed out")
+
+	// ErrNoProviders is returned when a configuration enables no provider.
+	ErrNoProviders = errors.New("no weather providers configured")
+
+	// ErrRateLimited is matched by a *RateLimitError, returned when a
+	// provider answered 429 Too Many Requests.
+	ErrRateLimited = errors.New("provider rate limited")
 )
+
+// RateLimitError reports a provider that rejected a request with 429.
+// It matches both ErrRateLimited and ErrProviderUnavailable, so callers
+// that only care about availability need not distinguish it.
+type RateLimitError struct {
+	// RetryAfter is the delay the provider asked for, or 0 if it sent
+	// no usable Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return ErrRateLimited.Error() + ", retry after " + e.RetryAfter.String()
+	}
+	return ErrRateLimited.Error()
+}
+
+// Is reports whether target is ErrRateLimited or ErrProviderUnavailable.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited || target == ErrProviderUnavailable
+}
+
+// rateLimitError returns a *RateLimitError for a 429 response received at
+// now. Retry-After may be given in seconds or as an HTTP date.
+func rateLimitError(resp *http.Response, now time.Time) error {
+	e := &RateLimitError{}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		e.RetryAfter = t.Sub(now)
+	}
+	return e
+}
+
+// requestError classifies a failed provider request: ErrTimeout if ctx is
+// done or the request hit a deadline, ErrProviderUnavailable otherwise.
+func requestError(ctx context.Context, err error) error {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return ErrTimeout
+	}
+	return ErrProviderUnavailable
+}
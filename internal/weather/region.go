@@ -0,0 +1,45 @@
+package weather
+
+import (
+	"slices"
+	"strings"
+)
+
+// Region restricts a provider to part of the world. A location with a
+// known country matches if the country is listed; Box is only consulted
+// when the country is unknown or the region lists no countries.
+type Region struct {
+	Countries []string // ISO 3166-1 alpha-2
+	Box       *BoundingBox
+}
+
+// BoundingBox is a latitude/longitude rectangle. It does not wrap
+// around the antimeridian.
+type BoundingBox struct {
+	MinLat, MaxLat float64
+	MinLon, MaxLon float64
+}
+
+// Contains reports whether loc lies within the box.
+func (b BoundingBox) Contains(loc Location) bool {
+	return loc.Lat >= b.MinLat && loc.Lat <= b.MaxLat &&
+		loc.Lon >= b.MinLon && loc.Lon <= b.MaxLon
+}
+
+// Contains reports whether loc is within the region. A nil region
+// covers everything.
+func (r *Region) Contains(loc Location) bool {
+	if r == nil {
+		return true
+	}
+	if loc.Country != "" && len(r.Countries) > 0 {
+		return slices.ContainsFunc(r.Countries, func(c string) bool {
+			return strings.EqualFold(c, loc.Country)
+		})
+	}
+	if r.Box != nil {
+		return r.Box.Contains(loc)
+	}
+	// Without a box, an unknown country cannot be ruled out.
+	return true
+}
@@ -0,0 +1,38 @@
+package weather
+
+import "testing"
+
+func TestNWSRegion(t *testing.T) {
+	tests := []struct {
+		name string
+		loc  Location
+		want bool
+	}{
+		{"US city", Location{Name: "New York", Lat: 40.71, Lon: -74.01, Country: "US"}, true},
+		{"US territory outside the box", Location{Name: "Hagåtña", Lat: 13.48, Lon: 144.75, Country: "GU"}, true},
+		{"Canadian city inside the box", Location{Name: "Toronto", Lat: 43.65, Lon: -79.38, Country: "CA"}, false},
+		{"Mexican city inside the box", Location{Name: "Monterrey", Lat: 25.69, Lon: -100.32, Country: "MX"}, false},
+		{"no country inside the box", Location{Name: "Denver", Lat: 39.74, Lon: -104.99}, true},
+		{"no country outside the box", Location{Name: "London", Lat: 51.51, Lon: -0.13}, false},
+	}
+
+	for _, tt := range tests {
+		if got := nwsRegion.Contains(tt.loc); got != tt.want {
+			t.Errorf("%s: Contains = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRegionWithoutCountries(t *testing.T) {
+	r := &Region{Box: &BoundingBox{MinLat: 0, MaxLat: 10, MinLon: 0, MaxLon: 10}}
+
+	if !r.Contains(Location{Lat: 5, Lon: 5, Country: "FR"}) {
+		t.Error("box-only region rejected a location inside the box")
+	}
+	if r.Contains(Location{Lat: 20, Lon: 5, Country: "FR"}) {
+		t.Error("box-only region accepted a location outside the box")
+	}
+	if !(&Region{Countries: []string{"US"}}).Contains(Location{Name: "Somewhere"}) {
+		t.Error("countries-only region rejected a location without a country")
+	}
+}
@@ -0,0 +1,46 @@
+package weather
+
+import (
+	"context"
+	"net/http"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the correlation ID of the
+// request it serves.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID carried by ctx, or "" if none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDTransport sets the correlation ID from the request context as
+// Header on outbound requests, so upstream logs can be matched with ours.
+// Requests without an ID, or already carrying the header, pass unchanged.
+type RequestIDTransport struct {
+	Header string
+	Base   http.RoundTripper // nil means http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	id := RequestID(req.Context())
+	if id == "" || t.Header == "" || req.Header.Get(t.Header) != "" {
+		return base.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(req.Context())
+	req.Header.Set(t.Header, id)
+	return base.RoundTrip(req)
+}
@@ -4,11 +4,161 @@ import (
 	"context"
 	"errors"
 	"log/slog"
-	"sync"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
 )
 
 type Service struct {
-	providers []Provider
+	// providers is swapped as a whole by SetProviders; a call loads it
+	// once, so in-flight fan-outs keep the set they started with.
+	providers     atomic.Pointer[[]Provider]
+	priority      []Source
+	strategy      string
+	weights       Weights
+	minProviders  int
+	fanoutTimeout time.Duration
+	fanoutStagger time.Duration
+	sequential    bool
+	feelsLike     bool
+	synthetic     bool
+	resolver      CoordinateResolver
+	lang          string
+	latency       *latencyTracker
+	clock         clock.Clock
+
+	// Identical calls in flight share one fan-out; see flightGroup.
+	coalesceWindow  time.Duration
+	currentFlights  flightGroup[CurrentWeather]
+	forecastFlights flightGroup[Forecast]
+}
+
+// Option configures optional Service behavior.
+type Option func(*Service)
+
+// WithMinProviders sets the quorum of providers that must succeed for an
+// aggregate to be returned. Providers that cannot serve a request (per their
+// Capabilities) do not count towards the required total.
+func WithMinProviders(n int) Option {
+	return func(s *Service) {
+		s.minProviders = max(n, 1)
+	}
+}
+
+// WithSourcePriority sets the order in which sources are preferred when
+// aggregation picks a single result. Sources not listed rank after listed
+// ones; an empty list keeps DefaultSourcePriority.
+func WithSourcePriority(priority []Source) Option {
+	return func(s *Service) {
+		if len(priority) > 0 {
+			s.priority = priority
+		}
+	}
+}
+
+// WithAggregation selects the aggregation strategy (StrategyFirst or
+// StrategyWeighted) and the per-source weights used by the latter.
+// Unknown strategies keep StrategyFirst.
+func WithAggregation(strategy string, weights Weights) Option {
+	return func(s *Service) {
+		if strategy == StrategyWeighted {
+			s.strategy = strategy
+		}
+		s.weights = weights
+	}
+}
+
+// WithFanoutTimeout bounds how long a call waits for provider results.
+// Results that arrive by the deadline are aggregated; slower providers are
+// logged and counted as failed. Zero disables the bound, leaving only the
+// caller's context deadline.
+func WithFanoutTimeout(d time.Duration) Option {
+	return func(s *Service) {
+		s.fanoutTimeout = d
+	}
+}
+
+// WithResolver enables region filtering: the city is resolved once per
+// call and providers whose Capabilities.Region does not contain it are
+// skipped. Without a resolver every provider is called.
+func WithResolver(r CoordinateResolver) Option {
+	return func(s *Service) {
+		s.resolver = r
+	}
+}
+
+// WithDefaultLanguage sets the description language requested from
+// providers when the context does not ask for one (see WithLanguage).
+// Empty keeps the providers' default, DefaultLanguage.
+func WithDefaultLanguage(lang string) Option {
+	return func(s *Service) {
+		s.lang = lang
+	}
+}
+
+// WithLatencyBudget enables degraded mode: providers whose rolling p95
+// latency exceeds budget are skipped (but periodically probed) until they
+// answer within budget again. Zero disables it.
+func WithLatencyBudget(budget time.Duration) Option {
+	return func(s *Service) {
+		s.latency.budget = budget
+	}
+}
+
+// WithCoalesceWindow keeps sharing a successful fan-out with identical
+// calls (same city, days and language) that start up to d after it
+// finished. Calls overlapping a fan-out always share it; zero limits
+// coalescing to those.
+func WithCoalesceWindow(d time.Duration) Option {
+	return func(s *Service) {
+		s.coalesceWindow = max(d, 0)
+	}
+}
+
+// maxFanoutStagger caps WithFanoutStagger so staggering stays a small
+// fraction of a request's latency.
+const maxFanoutStagger = 250 * time.Millisecond
+
+// WithFanoutStagger delays each provider call by a random duration in
+// [0, d] to avoid hitting a shared upstream or proxy with a burst.
+// d is capped at maxFanoutStagger; zero disables staggering.
+func WithFanoutStagger(d time.Duration) Option {
+	return func(s *Service) {
+		s.fanoutStagger = min(max(d, 0), maxFanoutStagger)
+	}
+}
+
+// WithSequentialFanout calls providers one at a time in their declared
+// order instead of concurrently, and disables staggering, so results
+// arrive in a deterministic order. It is meant for tests asserting which
+// source an aggregate came from; the fan-out timeout no longer bounds
+// the calls.
+func WithSequentialFanout() Option {
+	return func(s *Service) {
+		s.sequential = true
+	}
+}
+
+// WithFeelsLike sets whether aggregated current weather carries a
+// FeelsLike temperature derived from the aggregated temperature, humidity
+// and wind speed, so it does not depend on which providers answered.
+func WithFeelsLike(enabled bool) Option {
+	return func(s *Service) {
+		s.feelsLike = enabled
+	}
+}
+
+// WithSyntheticForecast sets whether a forecast request that no forecast
+// provider could serve falls back to a SyntheticForecast built from
+// current weather, instead of failing.
+func WithSyntheticForecast(enabled bool) Option {
+	return func(s *Service) {
+		s.synthetic = enabled
+	}
 }
 
 type result[T any] struct {
@@ -17,58 +167,110 @@ type result[T any] struct {
 	err      error
 }
 
-func NewService(providers []Provider) *Service {
-	return &Service{
-		providers: providers,
+// NewService creates a Service querying the given providers.
+// If clk is nil, the real clock is used.
+func NewService(providers []Provider, clk clock.Clock, opts ...Option) *Service {
+	s := &Service{
+		priority:     DefaultSourcePriority,
+		strategy:     StrategyFirst,
+		minProviders: 1,
+		latency:      newLatencyTracker(0),
+		clock:        clock.OrReal(clk),
+	}
+	s.providers.Store(&providers)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetProviders replaces the providers used by subsequent calls, e.g. after
+// API keys were rotated. Calls already in progress finish with the
+// previous set.
+func (s *Service) SetProviders(providers []Provider) {
+	s.providers.Store(&providers)
+}
+
+// Providers returns the providers currently in use.
+func (s *Service) Providers() []Provider {
+	return *s.providers.Load()
+}
+
+// Language returns the description language requested by default.
+func (s *Service) Language() string {
+	if s.lang == "" {
+		return DefaultLanguage
 	}
+	return s.lang
+}
+
+// withLanguage applies the default language unless ctx requests one.
+func (s *Service) withLanguage(ctx context.Context) context.Context {
+	if Language(ctx) == "" && s.lang != "" {
+		return WithLanguage(ctx, s.lang)
+	}
+	return ctx
+}
+
+// Report describes which providers took part in a Service call.
+type Report struct {
+	Called    []Source `json:"called"`
+	Succeeded []Source `json:"succeeded"`
+	Failed    []Source `json:"failed"`
 }
 
 // GetCurrentWeather concurrently fetches current weather from all providers,
 // logs individual provider errors and aggregates successful results.
 func (s *Service) GetCurrentWeather(ctx context.Context, city string) (CurrentWeather, error) {
-	if len(s.providers) == 0 {
-		return CurrentWeather{}, ErrProviderUnavailable
+	w, _, err := s.GetCurrentWeatherWithReport(ctx, city)
+	return w, err
+}
+
+// GetCurrentWeatherWithReport is like GetCurrentWeather but also reports
+// which providers were called and how they fared. Identical calls made
+// while one is in progress, e.g. by the scheduler and the API, wait for
+// it and share its outcome instead of calling providers again.
+func (s *Service) GetCurrentWeatherWithReport(ctx context.Context, city string) (CurrentWeather, Report, error) {
+	ctx = s.withLanguage(ctx)
+	return s.currentFlights.do(ctx, flightKey(ctx, city, 0), s.coalesceWindow, s.clock.Now,
+		func() (CurrentWeather, Report, error) { return s.fetchCurrent(ctx, city) })
+}
+
+// fetchCurrent fans out a current weather request to the eligible
+// providers and aggregates their results.
+func (s *Service) fetchCurrent(ctx context.Context, city string) (CurrentWeather, Report, error) {
+	var report Report
+
+	providers := s.withinBudget(s.notDegraded(s.inRegion(ctx, city, s.eligible(func(c Capabilities) bool { return c.Current }))))
+	if len(providers) == 0 {
+		return CurrentWeather{}, report, ErrProviderUnavailable
 	}
+	report.Called = sourcesOf(providers)
 
-	resultsCh := make(chan result[CurrentWeather], len(s.providers))
-	var wg sync.WaitGroup
+	resultsCh := fanOut(ctx, providers, s.fanoutStagger, s.sequential, func(p Provider) (CurrentWeather, error) {
+		slog.Info("fetching current weather",
+			"provider", p.Name(),
+			"city", city,
+		)
+		defer s.observe(p, s.clock.Now())
 
-	for _, prov := range s.providers {
-		p := prov // capture, because WaitGroup.Go is not "go func()"
-		wg.Go(func() {
-			slog.Info("fetching current weather",
+		w, err := p.FetchCurrent(ctx, city)
+		if err == nil && looksEmptyFor(p, w) {
+			slog.Warn("provider returned an empty placeholder reading",
 				"provider", p.Name(),
 				"city", city,
 			)
+			return CurrentWeather{}, ErrProviderUnavailable
+		}
+		return w, err
+	})
 
-			w, err := p.FetchCurrent(ctx, city)
-
-			resultsCh <- result[CurrentWeather]{
-				provider: p,
-				data:     w,
-				err:      err,
-			}
-		})
-	}
-
-	go func() {
-		wg.Wait()
-		close(resultsCh)
-	}()
-
-	var (
-		successes []CurrentWeather
-		lastErr   error
-	)
+	succeeded, failed, lastErr := collect(resultsCh, providers, s.fanoutTimeout, "current", city)
+	report.Failed = failed
 
-	for res := range resultsCh {
-		if res.err != nil {
-			logProviderError("current", res.provider, city, res.err)
-			lastErr = res.err
-			continue
-		}
-		successes = append(successes, res.data)
-	}
+	// Results arrive in completion order; make aggregation deterministic.
+	successes := sortedData(succeeded, s.priority)
+	report.Succeeded = sourcesOfResults(succeeded)
 
 	if len(successes) == 0 {
 		if lastErr != nil {
@@ -77,74 +279,455 @@ func (s *Service) GetCurrentWeather(ctx context.Context, city string) (CurrentWe
 				"error", lastErr,
 			)
 		}
-		return CurrentWeather{}, ErrProviderUnavailable
+		return CurrentWeather{}, report, allFailedErr(ctx, lastErr)
 	}
 
-	agg := AggregateCurrentWeather(successes)
-	return agg, nil
+	if !s.quorumMet(len(successes), len(providers)) {
+		slog.Warn("not enough providers succeeded for current weather",
+			"city", city,
+			"succeeded", len(successes),
+			"required", min(s.minProviders, len(providers)),
+			"failed", report.Failed,
+		)
+		return CurrentWeather{}, report, ErrProviderUnavailable
+	}
+
+	aggregate := AggregateCurrentWeather
+	if s.strategy == StrategyWeighted {
+		aggregate = func(rs []CurrentWeather) (CurrentWeather, error) {
+			return AggregateCurrentWeatherWeighted(rs, s.weights)
+		}
+	}
+	agg, err := aggregate(successes)
+	if err != nil {
+		slog.Warn("no valid provider results for current weather",
+			"city", city,
+			"error", err,
+		)
+		return CurrentWeather{}, report, err
+	}
+	agg.Aggregation = aggregationMethod(s.strategy, agg.Sources)
+	if agg.Language == "" {
+		agg.Language = DefaultLanguage
+	}
+	if s.feelsLike {
+		feels := FeelsLike(agg.Temperature, agg.Humidity, agg.WindSpeed)
+		agg.FeelsLike = &feels
+	}
+	return agg, report, nil
 }
 
 // GetForecast concurrently fetches forecast data from all providers,
 // logs individual provider errors and aggregates successful results.
 func (s *Service) GetForecast(ctx context.Context, city string, days int) (Forecast, error) {
-	if len(s.providers) == 0 {
-		return Forecast{}, ErrProviderUnavailable
+	fc, _, err := s.GetForecastWithReport(ctx, city, days)
+	return fc, err
+}
+
+// GetForecastWithReport is like GetForecast but also reports which
+// providers were called and how they fared. Identical calls are
+// coalesced as in GetCurrentWeatherWithReport.
+func (s *Service) GetForecastWithReport(ctx context.Context, city string, days int) (Forecast, Report, error) {
+	ctx = s.withLanguage(ctx)
+	return s.forecastFlights.do(ctx, flightKey(ctx, city, days), s.coalesceWindow, s.clock.Now,
+		func() (Forecast, Report, error) { return s.forecastOrSynthetic(ctx, city, days) })
+}
+
+// forecastOrSynthetic fetches a forecast and, if that fails for a reason
+// other than an unknown city or the caller giving up, falls back to a
+// synthetic one when enabled. The report describes the forecast fan-out.
+func (s *Service) forecastOrSynthetic(ctx context.Context, city string, days int) (Forecast, Report, error) {
+	fc, report, err := s.fetchForecast(ctx, city, days)
+	if err == nil || !s.synthetic || errors.Is(err, ErrCityNotFound) || ctx.Err() != nil {
+		return fc, report, err
 	}
 
-	resultsCh := make(chan result[Forecast], len(s.providers))
-	var wg sync.WaitGroup
+	cw, _, cerr := s.GetCurrentWeatherWithReport(ctx, city)
+	if cerr != nil {
+		return fc, report, err
+	}
+	slog.Warn("no forecast provider succeeded, serving a synthetic forecast from current weather",
+		"city", city,
+		"days", days,
+		"error", err,
+	)
+	return SyntheticForecast(cw, days), report, nil
+}
 
-	for _, prov := range s.providers {
-		p := prov
-		wg.Go(func() {
-			slog.Info("fetching forecast",
-				"provider", p.Name(),
+// fetchForecast fans out a forecast request to the eligible providers
+// and aggregates their results.
+func (s *Service) fetchForecast(ctx context.Context, city string, days int) (Forecast, Report, error) {
+	var report Report
+
+	// Providers with a shorter horizon are asked for what they can serve.
+	providers := s.withinBudget(s.notDegraded(s.inRegion(ctx, city, s.eligible(func(c Capabilities) bool { return c.Forecast }))))
+	if len(providers) == 0 {
+		return Forecast{}, report, ErrProviderUnavailable
+	}
+	report.Called = sourcesOf(providers)
+
+	resultsCh := fanOut(ctx, providers, s.fanoutStagger, s.sequential, func(p Provider) (Forecast, error) {
+		pDays := CapabilitiesOf(p).forecastDays(days)
+		slog.Info("fetching forecast",
+			"provider", p.Name(),
+			"city", city,
+			"days", pDays,
+		)
+		defer s.observe(p, s.clock.Now())
+		return p.FetchForecast(ctx, city, pDays)
+	})
+
+	succeeded, failed, lastErr := collect(resultsCh, providers, s.fanoutTimeout, "forecast", city)
+	report.Failed = failed
+
+	// Results arrive in completion order; make aggregation deterministic.
+	successes := sortedData(succeeded, s.priority)
+	report.Succeeded = sourcesOfResults(succeeded)
+
+	if len(successes) == 0 {
+		if lastErr != nil {
+			slog.Warn("all providers failed for forecast",
 				"city", city,
 				"days", days,
+				"error", lastErr,
 			)
+		}
+		return Forecast{}, report, allFailedErr(ctx, lastErr)
+	}
 
-			fc, err := p.FetchForecast(ctx, city, days)
+	if !s.quorumMet(len(successes), len(providers)) {
+		slog.Warn("not enough providers succeeded for forecast",
+			"city", city,
+			"days", days,
+			"succeeded", len(successes),
+			"required", min(s.minProviders, len(providers)),
+			"failed", report.Failed,
+		)
+		return Forecast{}, report, ErrProviderUnavailable
+	}
 
-			resultsCh <- result[Forecast]{
+	aggregate := AggregateForecast
+	if s.strategy == StrategyWeighted {
+		aggregate = func(rs []Forecast) (Forecast, error) {
+			return AggregateForecastWeighted(rs, s.weights)
+		}
+	}
+	agg, err := aggregate(successes)
+	if err != nil {
+		slog.Warn("no valid provider results for forecast",
+			"city", city,
+			"days", days,
+			"error", err,
+		)
+		return Forecast{}, report, err
+	}
+	agg.Days = days
+	agg.UpdatedAt = s.clock.Now().UTC()
+	agg.Aggregation = aggregationMethod(s.strategy, agg.Sources)
+	if agg.Language == "" {
+		agg.Language = DefaultLanguage
+	}
+	return agg, report, nil
+}
+
+// fanOut calls fetch for every provider concurrently, each after a random
+// delay of up to stagger, or one at a time in order if sequential. The
+// returned channel is buffered and receives exactly one result per
+// provider; it is never closed, so callers receive len(providers) times
+// instead of ranging. This avoids a WaitGroup and a
+// closer goroutine per request.
+func fanOut[T any](ctx context.Context, providers []Provider, stagger time.Duration, sequential bool, fetch func(Provider) (T, error)) <-chan result[T] {
+	resultsCh := make(chan result[T], len(providers))
+
+	if sequential {
+		// The channel holds every result, so this never blocks.
+		for _, p := range providers {
+			data, err := fetch(p)
+			resultsCh <- result[T]{provider: p, data: data, err: err}
+		}
+		return resultsCh
+	}
+
+	for _, p := range providers {
+		go func() {
+			if err := sleepJitter(ctx, stagger); err != nil {
+				resultsCh <- result[T]{provider: p, err: err}
+				return
+			}
+			data, err := fetch(p)
+			resultsCh <- result[T]{
 				provider: p,
-				data:     fc,
+				data:     data,
 				err:      err,
 			}
-		})
+		}()
 	}
 
-	go func() {
-		wg.Wait()
-		close(resultsCh)
-	}()
+	return resultsCh
+}
+
+// collect receives one result per provider until all have arrived or the
+// timeout (if positive) expires. Providers that missed the deadline are
+// reported as failed along with the ones that returned an error.
+// lastErr matches ErrCityNotFound only if every provider reported it, and
+// ErrRateLimited only if every provider was rate limited; it then carries
+// the longest delay asked for.
+func collect[T any](
+	resultsCh <-chan result[T],
+	providers []Provider,
+	timeout time.Duration,
+	op, city string,
+) (succeeded []result[T], failed []Source, lastErr error) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	succeeded = make([]result[T], 0, len(providers))
+	arrived := make(map[string]bool, len(providers))
 
+	// otherErr is the last failure other than ErrCityNotFound.
 	var (
-		successes []Forecast
-		lastErr   error
+		otherErr error
+		limited  int
+		retry    time.Duration
 	)
+	defer func() {
+		if otherErr != nil {
+			lastErr = otherErr
+		}
+		switch {
+		case limited > 0 && limited == len(failed):
+			lastErr = &RateLimitError{RetryAfter: retry}
+		case errors.Is(lastErr, ErrRateLimited):
+			lastErr = ErrProviderUnavailable
+		}
+	}()
+
+	for range providers {
+		select {
+		case res := <-resultsCh:
+			arrived[res.provider.Name()] = true
+			if res.err != nil {
+				logProviderError(op, res.provider, city, res.err)
+				failed = append(failed, Source(res.provider.Name()))
+				lastErr = res.err
+				if !errors.Is(res.err, ErrCityNotFound) {
+					otherErr = res.err
+				}
+				var rl *RateLimitError
+				if errors.As(res.err, &rl) {
+					limited++
+					retry = max(retry, rl.RetryAfter)
+				}
+				continue
+			}
+			succeeded = append(succeeded, res)
+
+		case <-deadline:
+			var late []Source
+			for _, p := range providers {
+				if !arrived[p.Name()] {
+					late = append(late, Source(p.Name()))
+				}
+			}
+			slog.Warn("providers missed fan-out deadline",
+				"op", op,
+				"city", city,
+				"timeout", timeout.String(),
+				"providers", late,
+			)
+			failed = append(failed, late...)
+			if otherErr == nil {
+				otherErr = context.DeadlineExceeded
+			}
+			return succeeded, failed, lastErr
+		}
+	}
+
+	return succeeded, failed, lastErr
+}
+
+// allFailedErr returns the error reported when no provider succeeded:
+// ErrCityNotFound if every provider said so, a *RateLimitError if every
+// provider was rate limited, ErrTimeout if ctx expired or providers were
+// cut off by a deadline, ErrProviderUnavailable otherwise.
+func allFailedErr(ctx context.Context, lastErr error) error {
+	switch {
+	case errors.Is(lastErr, ErrCityNotFound):
+		return ErrCityNotFound
+	case errors.Is(lastErr, ErrRateLimited):
+		return lastErr
+	case ctx.Err() != nil, errors.Is(lastErr, ErrTimeout), errors.Is(lastErr, context.DeadlineExceeded):
+		return ErrTimeout
+	default:
+		return ErrProviderUnavailable
+	}
+}
+
+// sleepJitter waits for a random duration in [0, d], or until ctx is done.
+func sleepJitter(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(rand.N(d + 1))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
-	for res := range resultsCh {
-		if res.err != nil {
-			logProviderError("forecast", res.provider, city, res.err)
-			lastErr = res.err
+// eligible returns the providers whose capabilities satisfy accept.
+func (s *Service) eligible(accept func(Capabilities) bool) []Provider {
+	providers := s.Providers()
+	res := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		if accept(CapabilitiesOf(p)) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// inRegion drops providers whose region does not contain the city.
+// If no provider is region-restricted, or the city cannot be resolved,
+// providers are returned unchanged.
+func (s *Service) inRegion(ctx context.Context, city string, providers []Provider) []Provider {
+	if s.resolver == nil || !slices.ContainsFunc(providers, func(p Provider) bool {
+		return CapabilitiesOf(p).Region != nil
+	}) {
+		return providers
+	}
+
+	loc, err := s.resolver.Resolve(ctx, city)
+	if err != nil {
+		// Let the providers report the failure themselves.
+		return providers
+	}
+
+	res := providers[:0]
+	for _, p := range providers {
+		if CapabilitiesOf(p).Region.Contains(loc) {
+			res = append(res, p)
 			continue
 		}
-		successes = append(successes, res.data)
+		slog.Debug("skipping provider outside its region",
+			"provider", p.Name(),
+			"city", city,
+			"country", loc.Country,
+		)
 	}
+	return res
+}
 
-	if len(successes) == 0 {
-		if lastErr != nil {
-			slog.Warn("all providers failed for forecast",
-				"city", city,
-				"days", days,
-				"error", lastErr,
+// notDegraded drops providers that are over the latency budget, unless
+// that would leave none to call.
+func (s *Service) notDegraded(providers []Provider) []Provider {
+	now := s.clock.Now()
+	res := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		if s.latency.allow(p.Name(), now) {
+			res = append(res, p)
+		}
+	}
+	if len(res) == 0 {
+		return providers
+	}
+	return res
+}
+
+// withinBudget drops providers whose rate-limit budget is exhausted.
+// Unlike notDegraded it never falls back to the full list: calling an
+// upstream past its limit risks getting the instance banned.
+func (s *Service) withinBudget(providers []Provider) []Provider {
+	res := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		if b, ok := BudgetOf(p); ok && b.Exhausted() {
+			slog.Warn("skipping provider with exhausted rate-limit budget",
+				"provider", p.Name(),
+				"used", b.Used,
+				"reset_at", b.ResetAt,
 			)
+			continue
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// observe records the latency of a provider call started at start.
+func (s *Service) observe(p Provider, start time.Time) {
+	now := s.clock.Now()
+	s.latency.record(p.Name(), now.Sub(start), now)
+}
+
+// ProviderStatus returns the recent latency and degraded state of every
+// configured provider.
+func (s *Service) ProviderStatus() []ProviderStatus {
+	providers := s.Providers()
+	res := s.latency.status(sourceNames(providers))
+	for i, p := range providers {
+		res[i].Capabilities = CapabilitiesOf(p)
+		if b, ok := BudgetOf(p); ok {
+			res[i].Budget = &b
 		}
-		return Forecast{}, ErrProviderUnavailable
 	}
+	return res
+}
 
-	agg := AggregateForecast(successes)
-	return agg, nil
+// quorumMet reports whether enough of the eligible providers succeeded.
+func (s *Service) quorumMet(succeeded, eligible int) bool {
+	return succeeded >= min(s.minProviders, eligible)
+}
+
+func sourcesOf(providers []Provider) []Source {
+	res := make([]Source, 0, len(providers))
+	for _, p := range providers {
+		res = append(res, Source(p.Name()))
+	}
+	return res
+}
+
+func sourceNames(providers []Provider) []string {
+	res := make([]string, 0, len(providers))
+	for _, p := range providers {
+		res = append(res, p.Name())
+	}
+	return res
+}
+
+func sourcesOfResults[T any](rs []result[T]) []Source {
+	res := make([]Source, 0, len(rs))
+	for _, r := range rs {
+		res = append(res, Source(r.provider.Name()))
+	}
+	return res
+}
+
+// sortedData orders successful results by source priority (then by provider
+// name) and returns their payloads.
+func sortedData[T any](rs []result[T], priority []Source) []T {
+	slices.SortStableFunc(rs, func(a, b result[T]) int {
+		ra := sourceRank(Source(a.provider.Name()), priority)
+		rb := sourceRank(Source(b.provider.Name()), priority)
+		if ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a.provider.Name(), b.provider.Name())
+	})
+
+	data := make([]T, 0, len(rs))
+	for _, r := range rs {
+		data = append(data, r.data)
+	}
+	return data
 }
 
 func logProviderError(op string, p Provider, city string, err error) {
@@ -156,6 +739,13 @@ func logProviderError(op string, p Provider, city string, err error) {
 			"city", city,
 			"error", err)
 
+	case errors.Is(err, ErrTimeout):
+		slog.Warn("provider timed out",
+			"op", op,
+			"provider", p.Name(),
+			"city", city,
+			"error", err)
+
 	case errors.Is(err, ErrCityNotFound):
 		slog.Warn("city not found for provider",
 			"op", op,
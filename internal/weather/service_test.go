@@ -0,0 +1,252 @@
+package weather_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/clock"
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// TestMain silences provider and service logging.
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// fakeProvider returns fixed results after an optional delay.
+type fakeProvider struct {
+	source   weather.Source
+	current  weather.CurrentWeather
+	forecast weather.Forecast
+	err      error
+	delay    time.Duration
+
+	calls atomic.Int32
+}
+
+func (p *fakeProvider) Name() string { return string(p.source) }
+
+func (p *fakeProvider) FetchCurrent(ctx context.Context, city string) (weather.CurrentWeather, error) {
+	p.calls.Add(1)
+	if err := p.wait(ctx); err != nil {
+		return weather.CurrentWeather{}, err
+	}
+	w := p.current
+	w.City = city
+	w.Source = p.source
+	return w, nil
+}
+
+func (p *fakeProvider) FetchForecast(ctx context.Context, city string, days int) (weather.Forecast, error) {
+	p.calls.Add(1)
+	if err := p.wait(ctx); err != nil {
+		return weather.Forecast{}, err
+	}
+	fc := p.forecast
+	fc.City = city
+	fc.Days = days
+	fc.Source = p.source
+	return fc, nil
+}
+
+func (p *fakeProvider) wait(ctx context.Context) error {
+	if p.delay > 0 {
+		select {
+		case <-time.After(p.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return p.err
+}
+
+// reading returns a current weather result for source.
+func reading(source weather.Source, temp float64, cond weather.ConditionCode) *fakeProvider {
+	return &fakeProvider{
+		source: source,
+		current: weather.CurrentWeather{
+			Temperature: temp,
+			Humidity:    80,
+			WindSpeed:   4,
+			Condition:   cond,
+			ObservedAt:  testNow,
+		},
+	}
+}
+
+func TestGetCurrentWeatherIgnoresProviderOrder(t *testing.T) {
+	a := reading(weather.SourceOpenMeteo, 10, weather.ConditionRain)
+	b := reading(weather.SourceOpenWeather, 11, weather.ConditionCloudy)
+	c := reading(weather.SourceWeatherAPI, 12, weather.ConditionClear)
+
+	// The delays make completion order differ from both input orders.
+	a.delay, b.delay, c.delay = 30*time.Millisecond, 0, 15*time.Millisecond
+
+	var results []weather.CurrentWeather
+	for _, providers := range [][]weather.Provider{{a, b, c}, {c, a, b}, {b, c, a}} {
+		svc := weather.NewService(providers, clock.NewManual(testNow))
+		w, err := svc.GetCurrentWeather(context.Background(), "London")
+		if err != nil {
+			t.Fatalf("GetCurrentWeather: %v", err)
+		}
+		results = append(results, w)
+	}
+
+	for i := 1; i < len(results); i++ {
+		if !reflect.DeepEqual(results[0], results[i]) {
+			t.Errorf("order %d: got %+v, want %+v", i, results[i], results[0])
+		}
+	}
+}
+
+func BenchmarkGetCurrentWeather(b *testing.B) {
+	providers := []weather.Provider{
+		reading(weather.SourceOpenMeteo, 10, weather.ConditionRain),
+		reading(weather.SourceOpenWeather, 11, weather.ConditionRain),
+		reading(weather.SourceWeatherAPI, 12, weather.ConditionCloudy),
+	}
+	svc := weather.NewService(providers, clock.NewManual(testNow))
+	ctx := context.Background()
+
+	for b.Loop() {
+		if _, err := svc.GetCurrentWeather(ctx, "London"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetCurrentWeatherAllRateLimited(t *testing.T) {
+	a := reading(weather.SourceOpenMeteo, 10, weather.ConditionRain)
+	b := reading(weather.SourceOpenWeather, 11, weather.ConditionRain)
+	a.err = &weather.RateLimitError{RetryAfter: 10 * time.Second}
+	b.err = &weather.RateLimitError{RetryAfter: 40 * time.Second}
+
+	svc := weather.NewService([]weather.Provider{a, b}, clock.NewManual(testNow))
+	_, err := svc.GetCurrentWeather(context.Background(), "London")
+
+	var rl *weather.RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("err = %v, want a *RateLimitError", err)
+	}
+	if rl.RetryAfter != 40*time.Second {
+		t.Errorf("RetryAfter = %v, want the longest provider delay 40s", rl.RetryAfter)
+	}
+}
+
+func TestGetCurrentWeatherPartlyRateLimited(t *testing.T) {
+	a := reading(weather.SourceOpenMeteo, 10, weather.ConditionRain)
+	b := reading(weather.SourceOpenWeather, 11, weather.ConditionRain)
+	a.err = &weather.RateLimitError{RetryAfter: 10 * time.Second}
+	b.err = weather.ErrProviderUnavailable
+
+	svc := weather.NewService([]weather.Provider{a, b}, clock.NewManual(testNow))
+	_, err := svc.GetCurrentWeather(context.Background(), "London")
+
+	if errors.Is(err, weather.ErrRateLimited) || !errors.Is(err, weather.ErrProviderUnavailable) {
+		t.Errorf("err = %v, want ErrProviderUnavailable", err)
+	}
+}
+
+func TestGetCurrentWeatherCityNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		errs []error
+		want error
+	}{
+		{"all not found", []error{weather.ErrCityNotFound, weather.ErrCityNotFound}, weather.ErrCityNotFound},
+		{"one unavailable", []error{weather.ErrCityNotFound, weather.ErrProviderUnavailable}, weather.ErrProviderUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var providers []weather.Provider
+			for i, err := range tt.errs {
+				p := reading(weather.DefaultSourcePriority[i], 10, weather.ConditionRain)
+				p.err = err
+				providers = append(providers, p)
+			}
+
+			svc := weather.NewService(providers, clock.NewManual(testNow))
+			if _, err := svc.GetCurrentWeather(context.Background(), "Atlantis"); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkGetForecast(b *testing.B) {
+	var providers []weather.Provider
+	for i, src := range weather.DefaultSourcePriority {
+		providers = append(providers, &fakeProvider{
+			source:   src,
+			forecast: hourlyForecast(src, 7*24, 10+float64(i)),
+		})
+	}
+	svc := weather.NewService(providers, clock.NewManual(testNow),
+		weather.WithAggregation(weather.StrategyWeighted, nil))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := svc.GetForecast(ctx, "London", 7); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// maintenanceProvider flags readings described as maintenance as empty,
+// whatever their values.
+type maintenanceProvider struct{ *fakeProvider }
+
+func (maintenanceProvider) LooksEmpty(w weather.CurrentWeather) bool {
+	return w.Description == "maintenance"
+}
+
+func TestEmptyPlaceholderReading(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider weather.Provider
+		wantErr  bool
+	}{
+		{"all zeros without a timestamp", &fakeProvider{source: weather.SourceOpenMeteo}, true},
+		{"0 °C and 0% with a timestamp", &fakeProvider{
+			source:  weather.SourceOpenMeteo,
+			current: weather.CurrentWeather{Condition: weather.ConditionClear, ObservedAt: testNow},
+		}, false},
+		{"provider check flags a real-looking reading", maintenanceProvider{&fakeProvider{
+			source:  weather.SourceOpenMeteo,
+			current: weather.CurrentWeather{Temperature: 12, Humidity: 60, Description: "maintenance", ObservedAt: testNow},
+		}}, true},
+		{"provider check accepts all zeros", maintenanceProvider{&fakeProvider{source: weather.SourceOpenMeteo}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := weather.NewService([]weather.Provider{tt.provider}, clock.NewManual(testNow))
+
+			w, err := svc.GetCurrentWeather(context.Background(), "London")
+			if tt.wantErr {
+				if !errors.Is(err, weather.ErrProviderUnavailable) {
+					t.Errorf("err = %v, want ErrProviderUnavailable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetCurrentWeather: %v", err)
+			}
+			if w.Temperature != 0 || w.Humidity != 0 {
+				t.Errorf("got %v °C, %d%%, want the zero reading", w.Temperature, w.Humidity)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package weather
+
+import "strings"
+
+// Canonical model units are °C for temperature, m/s for wind speed and %
+// for humidity. Providers declare their native units and convert at
+// mapping time.
+
+// TemperatureUnit is the unit a provider reports temperature in.
+type TemperatureUnit string
+
+const (
+	Celsius    TemperatureUnit = "C"
+	Fahrenheit TemperatureUnit = "F"
+)
+
+// SpeedUnit is the unit a provider reports wind speed in.
+type SpeedUnit string
+
+const (
+	MetresPerSecond   SpeedUnit = "m/s"
+	KilometresPerHour SpeedUnit = "km/h"
+	MilesPerHour      SpeedUnit = "mph"
+)
+
+// Units describes the native units of a provider payload.
+type Units struct {
+	Temperature TemperatureUnit
+	WindSpeed   SpeedUnit
+}
+
+// Native units of each provider, as requested from their APIs.
+var (
+	openMeteoUnits   = Units{Temperature: Celsius, WindSpeed: KilometresPerHour}
+	openWeatherUnits = Units{Temperature: Celsius, WindSpeed: MetresPerSecond} // units=metric
+	weatherAPIUnits  = Units{Temperature: Celsius, WindSpeed: KilometresPerHour}
+	nwsUnits         = Units{Temperature: Fahrenheit, WindSpeed: MilesPerHour}
+)
+
+// temperature converts v from u's temperature unit to °C.
+func (u Units) temperature(v float64) float64 {
+	if u.Temperature == Fahrenheit {
+		return fahrenheitToCelsius(v)
+	}
+	return v
+}
+
+// windSpeed converts v from u's wind speed unit to m/s.
+func (u Units) windSpeed(v float64) float64 {
+	switch u.WindSpeed {
+	case KilometresPerHour:
+		return kmhToMs(v)
+	case MilesPerHour:
+		return mphToMs(v)
+	default:
+		return v
+	}
+}
+
+// parseSpeedUnit maps a unit label such as "mph" or "kph" to a SpeedUnit.
+// Unrecognized labels fall back to def.
+func parseSpeedUnit(label string, def SpeedUnit) SpeedUnit {
+	switch strings.ToLower(label) {
+	case "m/s", "ms":
+		return MetresPerSecond
+	case "km/h", "kmh", "kph":
+		return KilometresPerHour
+	case "mph":
+		return MilesPerHour
+	default:
+		return def
+	}
+}
+
+// parseTemperatureUnit maps a unit label such as "F" to a TemperatureUnit.
+// Unrecognized labels fall back to def.
+func parseTemperatureUnit(label string, def TemperatureUnit) TemperatureUnit {
+	switch strings.ToUpper(label) {
+	case "C":
+		return Celsius
+	case "F":
+		return Fahrenheit
+	default:
+		return def
+	}
+}
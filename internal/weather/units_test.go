@@ -0,0 +1,26 @@
+package weather
+
+import (
+	"math"
+	"testing"
+)
+
+func TestUnitsConversion(t *testing.T) {
+	tests := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"18 km/h", openMeteoUnits.windSpeed(18), 5},
+		{"10 mph", nwsUnits.windSpeed(10), 4.4704},
+		{"5 m/s", openWeatherUnits.windSpeed(5), 5},
+		{"50 °F", nwsUnits.temperature(50), 10},
+		{"10 °C", openMeteoUnits.temperature(10), 10},
+	}
+
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
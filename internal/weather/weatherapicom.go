@@ -24,14 +24,25 @@ func (p *WeatherAPIComProvider) Name() string {
 	return string(SourceWeatherAPI)
 }
 
+// Capabilities reports supported request kinds.
+// The free WeatherAPI.com plan serves up to 3 days of forecast.
+func (p *WeatherAPIComProvider) Capabilities() Capabilities {
+	return Capabilities{Current: true, Forecast: true, MaxForecastDays: 3}
+}
+
 // FetchCurrent returns stubbed error for now.
-// Real implementation will call external API.
+// Real implementation will call external API, passing Language(ctx) as
+// the `lang` parameter and setting Language on the result. WeatherAPI
+// reports failures as {"error": {"code": ..., "message": ...}}, not always
+// with an error status, so the body must be checked after decoding: code
+// 1006 (no matching location) maps to ErrCityNotFound, anything else to
+// ErrProviderUnavailable. See the weatherapi_error fixture.
 func (p *WeatherAPIComProvider) FetchCurrent(ctx context.Context, city string) (CurrentWeather, error) {
 	return CurrentWeather{}, ErrProviderUnavailable
 }
 
 // FetchForecast returns stubbed error for now.
-// Real implementation will call external API.
+// Real implementation will call external API, localized like FetchCurrent.
 func (p *WeatherAPIComProvider) FetchForecast(ctx context.Context, city string, days int) (Forecast, error) {
 	return Forecast{}, ErrProviderUnavailable
 }
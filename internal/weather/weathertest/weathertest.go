@@ -0,0 +1,143 @@
+// Package weathertest provides recorded provider responses and an HTTP
+// server replaying them, so providers can be exercised offline.
+//
+// Point a provider at the server with its base URL option:
+//
+//	srv := weathertest.NewServer()
+//	defer srv.Close()
+//	p := weather.NewOpenMeteoProvider(nil, weathertest.Resolver{},
+//		weather.WithOpenMeteoBaseURL(srv.URL+weathertest.OpenMeteoPath))
+//
+// The OpenMeteo fixtures were recorded for London, the NWS ones for
+// New York.
+// The OpenWeatherMap and WeatherAPI fixtures describe the upstream schemas
+// for when those providers are implemented.
+//
+// Requests for InvalidCity replay the upstreams' error bodies instead.
+package weathertest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// Base paths of each upstream on the server returned by NewServer.
+const (
+	OpenMeteoPath      = "/openmeteo/v1"
+	NWSPath            = "/nws"
+	OpenWeatherMapPath = "/openweathermap/data/2.5"
+	WeatherAPIPath     = "/weatherapi/v1"
+)
+
+// baseURLPlaceholder is replaced with the server's NWS base URL in
+// fixtures that contain absolute links.
+const baseURLPlaceholder = "{{BASE_URL}}"
+
+// Fixture returns the named recorded response, e.g. "openmeteo_current".
+// It panics if the fixture does not exist.
+func Fixture(name string) []byte {
+	b, err := fixtures.ReadFile(path.Join("testdata", name+".json"))
+	if err != nil {
+		panic("weathertest: unknown fixture " + name)
+	}
+	return b
+}
+
+// NewServer starts a server replaying the fixtures for every upstream.
+// Unknown paths return 404. The caller must Close it.
+func NewServer() *httptest.Server {
+	mux := http.NewServeMux()
+	srv := httptest.NewUnstartedServer(mux)
+
+	mux.HandleFunc("GET "+OpenMeteoPath+"/forecast", func(w http.ResponseWriter, r *http.Request) {
+		if lat, err := strconv.ParseFloat(r.URL.Query().Get("latitude"), 64); err == nil && (lat < -90 || lat > 90) {
+			serveStatus(w, http.StatusBadRequest, Fixture("openmeteo_error"))
+			return
+		}
+		if r.URL.Query().Get("current_weather") == "true" {
+			serve(w, Fixture("openmeteo_current"))
+			return
+		}
+		serve(w, Fixture("openmeteo_forecast"))
+	})
+
+	mux.HandleFunc("GET "+NWSPath+"/points/{coords}", func(w http.ResponseWriter, r *http.Request) {
+		body := bytes.ReplaceAll(Fixture("nws_points"), []byte(baseURLPlaceholder), []byte(srv.URL+NWSPath))
+		serve(w, body)
+	})
+	mux.HandleFunc("GET "+NWSPath+"/gridpoints/{grid}/{xy}/forecast/hourly", func(w http.ResponseWriter, r *http.Request) {
+		serve(w, Fixture("nws_hourly"))
+	})
+
+	mux.HandleFunc("GET "+OpenWeatherMapPath+"/{endpoint}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("endpoint") {
+		case "weather":
+			serve(w, Fixture("openweathermap_current"))
+		case "forecast":
+			serve(w, Fixture("openweathermap_forecast"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc("GET "+WeatherAPIPath+"/{endpoint}", func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.URL.Query().Get("q"), InvalidCity) {
+			serveStatus(w, http.StatusBadRequest, Fixture("weatherapi_error"))
+			return
+		}
+		switch strings.TrimSuffix(r.PathValue("endpoint"), ".json") {
+		case "current":
+			serve(w, Fixture("weatherapi_current"))
+		case "forecast":
+			serve(w, Fixture("weatherapi_forecast"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	srv.Start()
+	return srv
+}
+
+// InvalidCity makes the server replay error responses. Resolver resolves
+// it to coordinates outside the valid range.
+const InvalidCity = "Invalid"
+
+// Resolver resolves the cities the fixtures were recorded for.
+type Resolver struct{}
+
+var locations = map[string]weather.Location{
+	"london":   {Name: "London", Lat: 51.5074, Lon: -0.1278, Country: "GB"},
+	"new york": {Name: "New York", Lat: 40.7128, Lon: -74.006, Country: "US"},
+	"invalid":  {Name: InvalidCity, Lat: 91, Lon: 0},
+}
+
+// Resolve returns the fixture location of city or weather.ErrCityNotFound.
+func (Resolver) Resolve(_ context.Context, city string) (weather.Location, error) {
+	loc, ok := locations[weather.NormalizeCity(city)]
+	if !ok {
+		return weather.Location{}, weather.ErrCityNotFound
+	}
+	return loc, nil
+}
+
+func serve(w http.ResponseWriter, body []byte) {
+	serveStatus(w, http.StatusOK, body)
+}
+
+func serveStatus(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,204 @@
+package weather
+
+import (
+	"math"
+	"slices"
+)
+
+// Aggregation strategies.
+const (
+	// StrategyFirst returns the highest-priority valid result.
+	StrategyFirst = "first"
+	// StrategyWeighted averages numeric fields weighted per source.
+	StrategyWeighted = "weighted"
+
+	// AggregationSingle reports an aggregate built from a single source,
+	// whatever the configured strategy.
+	AggregationSingle = "single"
+	// AggregationSynthetic reports a forecast synthesized from current
+	// weather.
+	AggregationSynthetic = "synthetic"
+)
+
+// aggregationMethod returns how an aggregate built by strategy from
+// sources was actually computed.
+func aggregationMethod(strategy string, sources []Source) string {
+	if len(sources) <= 1 {
+		return AggregationSingle
+	}
+	return strategy
+}
+
+// Weights maps a source to its trust weight in weighted aggregation.
+// Sources not listed weigh 1; a weight of 0 excludes the source from
+// averages while it is still listed in Sources.
+type Weights map[Source]float64
+
+func (w Weights) of(src Source) float64 {
+	if v, ok := w[src]; ok {
+		return v
+	}
+	return 1
+}
+
+// ParseWeights converts source names into Weights, returning the names
+// that do not match a known source separately.
+func ParseWeights(raw map[string]float64) (weights Weights, unknown []string) {
+	weights = make(Weights, len(raw))
+	for name, v := range raw {
+		srcs, bad := ParseSources([]string{name})
+		if len(bad) > 0 {
+			unknown = append(unknown, bad...)
+			continue
+		}
+		weights[srcs[0]] = v
+	}
+	return weights, unknown
+}
+
+// weightedMean accumulates a weighted mean of temperature, humidity and
+// wind speed.
+type weightedMean struct {
+	total       float64
+	temperature float64
+	humidity    float64
+	windSpeed   float64
+}
+
+func (m *weightedMean) add(weight, temperature float64, humidity int, windSpeed float64) {
+	if weight <= 0 {
+		return
+	}
+	m.total += weight
+	m.temperature += weight * temperature
+	m.humidity += weight * float64(humidity)
+	m.windSpeed += weight * windSpeed
+}
+
+// values returns the means; ok is false when nothing was added.
+func (m weightedMean) values() (temperature float64, humidity int, windSpeed float64, ok bool) {
+	if m.total == 0 {
+		return 0, 0, 0, false
+	}
+	return m.temperature / m.total,
+		int(math.Round(m.humidity / m.total)),
+		m.windSpeed / m.total,
+		true
+}
+
+// AggregateCurrentWeatherWeighted is like AggregateCurrentWeather but
+// replaces temperature, humidity and wind speed with their weighted mean
+// across the valid inputs. If every input weighs 0, the values of the
+// highest-priority input are kept.
+func AggregateCurrentWeatherWeighted(results []CurrentWeather, weights Weights) (CurrentWeather, error) {
+	results, excluded := validCurrent(results)
+	if len(results) == 0 {
+		return CurrentWeather{}, ErrProviderUnavailable
+	}
+	agg := firstCurrent(results)
+	agg.Quality = qualityOf(excluded)
+
+	var m weightedMean
+	for _, r := range results {
+		m.add(weights.of(r.Source), r.Temperature, r.Humidity, r.WindSpeed)
+	}
+	if t, h, w, ok := m.values(); ok {
+		agg.Temperature, agg.Humidity, agg.WindSpeed = t, h, w
+	}
+
+	return agg, nil
+}
+
+// AggregateForecastWeighted is like AggregateForecast but averages each
+// item of the highest-priority forecast with the items other forecasts
+// have for the same timestamp, except the gust, which is the strongest
+// one. Items keep their own values when every contributor weighs 0.
+// Merged items get the most-voted condition and, like in
+// AggregateCurrentWeather, its canonical description. Items are returned
+// in chronological order.
+func AggregateForecastWeighted(results []Forecast, weights Weights) (Forecast, error) {
+	valid, excluded := validForecasts(results)
+	if len(valid) == 0 {
+		return Forecast{}, ErrProviderUnavailable
+	}
+
+	// This runs for every forecast request, with a few providers of up to
+	// hundreds of items each. Rather than bucketing every item into a map
+	// by timestamp, walk the chronological series side by side with one
+	// cursor per forecast.
+	series := make([][]ForecastItem, len(valid))
+	for j, f := range valid {
+		series[j] = chronological(f.Items)
+	}
+	cursors := make([]int, len(series))
+
+	agg := valid[0]
+	items := make([]ForecastItem, len(series[0]))
+	var (
+		sources []Source
+		codes   = make([]ConditionCode, 0, len(series))
+		used    = make([]Source, 0, len(series))
+	)
+
+	for i, it := range series[0] {
+		var m weightedMean
+		codes, used = codes[:0], used[:0]
+
+		// The item contributes itself only, even if the highest-priority
+		// series repeats its timestamp.
+		m.add(weights.of(it.Source), it.Temperature, it.Humidity, it.WindSpeed)
+		codes = append(codes, conditionOf(it.Condition, it.Description))
+		used = appendSource(used, it.Source)
+
+		for j := 1; j < len(series); j++ {
+			s := series[j]
+			k := cursors[j]
+			for k < len(s) && s[k].TimeStamp.Before(it.TimeStamp) {
+				k++
+			}
+			cursors[j] = k
+
+			for ; k < len(s) && s[k].TimeStamp.Equal(it.TimeStamp); k++ {
+				other := s[k]
+				m.add(weights.of(other.Source), other.Temperature, other.Humidity, other.WindSpeed)
+				codes = append(codes, conditionOf(other.Condition, other.Description))
+				used = appendSource(used, other.Source)
+				it.WindGust = max(it.WindGust, other.WindGust)
+			}
+		}
+		if t, h, w, ok := m.values(); ok {
+			it.Temperature, it.Humidity, it.WindSpeed = t, h, w
+		}
+		it.Condition = dominantCondition(codes)
+		if len(used) > 1 {
+			it.Source = SourceAggregated
+			it.Description = mergedDescription(it.Condition, agg.Language, it.Description)
+		}
+		for _, src := range used {
+			sources = appendSource(sources, src)
+		}
+		items[i] = it
+	}
+
+	agg.Items = items
+	agg.ActualDays = DistinctDays(items)
+	agg.Sources = sources
+	agg.Source = forecastSource(agg.Source, sources)
+	agg.Quality = qualityOf(excluded)
+
+	return agg, nil
+}
+
+// chronological returns items sorted by timestamp. Provider series
+// normally are, in which case items is returned as is.
+func chronological(items []ForecastItem) []ForecastItem {
+	byTime := func(a, b ForecastItem) int {
+		return a.TimeStamp.Compare(b.TimeStamp)
+	}
+	if slices.IsSortedFunc(items, byTime) {
+		return items
+	}
+	sorted := slices.Clone(items)
+	slices.SortStableFunc(sorted, byTime)
+	return sorted
+}
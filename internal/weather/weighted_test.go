@@ -0,0 +1,94 @@
+package weather_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/andrqxa/weather-aggregator/internal/weather"
+)
+
+// hourlyForecast returns hours hourly items from testNow for source.
+func hourlyForecast(source weather.Source, hours int, temp float64) weather.Forecast {
+	items := make([]weather.ForecastItem, hours)
+	for h := range items {
+		items[h] = weather.ForecastItem{
+			TimeStamp:   testNow.Add(time.Duration(h) * time.Hour),
+			Temperature: temp,
+			Humidity:    80,
+			WindSpeed:   4,
+			Condition:   weather.ConditionCloudy,
+			Source:      source,
+		}
+	}
+	return weather.Forecast{City: "London", Days: hours / 24, Items: items, Source: source}
+}
+
+func TestAggregateForecastWeightedDuplicateTimestamps(t *testing.T) {
+	primary := hourlyForecast(weather.SourceOpenMeteo, 2, 10)
+	// The primary series repeats its first timestamp.
+	primary.Items[1].TimeStamp = primary.Items[0].TimeStamp
+	primary.Items[1].Temperature = 16
+	other := hourlyForecast(weather.SourceOpenWeather, 1, 30)
+
+	agg, err := weather.AggregateForecastWeighted([]weather.Forecast{primary, other}, nil)
+	if err != nil {
+		t.Fatalf("AggregateForecastWeighted: %v", err)
+	}
+
+	if len(agg.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(agg.Items))
+	}
+	// Each primary item is averaged with the other forecast only, not
+	// with its duplicate.
+	for i, want := range []float64{20, 23} {
+		if got := agg.Items[i].Temperature; math.Abs(got-want) > 1e-9 {
+			t.Errorf("item %d temperature = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestAggregateForecastWeighted(t *testing.T) {
+	a := hourlyForecast(weather.SourceOpenMeteo, 3, 10)
+	b := hourlyForecast(weather.SourceOpenWeather, 3, 20)
+	weights := weather.Weights{weather.SourceOpenMeteo: 3, weather.SourceOpenWeather: 1}
+
+	agg, err := weather.AggregateForecastWeighted([]weather.Forecast{a, b}, weights)
+	if err != nil {
+		t.Fatalf("AggregateForecastWeighted: %v", err)
+	}
+
+	for i, it := range agg.Items {
+		if math.Abs(it.Temperature-12.5) > 1e-9 || it.Source != weather.SourceAggregated {
+			t.Errorf("item %d = %+v, want 12.5 °C from %s", i, it, weather.SourceAggregated)
+		}
+	}
+	if len(agg.Sources) != 2 {
+		t.Errorf("sources = %v, want both", agg.Sources)
+	}
+}
+
+func BenchmarkAggregateForecast(b *testing.B) {
+	results := []weather.Forecast{
+		hourlyForecast(weather.SourceOpenMeteo, 7*24, 10),
+		hourlyForecast(weather.SourceOpenWeather, 7*24, 11),
+		hourlyForecast(weather.SourceWeatherAPI, 7*24, 12),
+	}
+
+	b.Run("first", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := weather.AggregateForecast(results); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("weighted", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := weather.AggregateForecastWeighted(results, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}